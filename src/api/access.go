@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+// maxDecisionWait caps how long WaitAccessDecisions holds an IPC call open.
+const maxDecisionWait = 60 * time.Second
+
+// decisionNotifier wakes up callers waiting for access request decisions.
+type decisionNotifier struct {
+	mu sync.Mutex
+	ch chan struct{}
+}
+
+func newDecisionNotifier() *decisionNotifier {
+	return &decisionNotifier{ch: make(chan struct{})}
+}
+
+// wait returns a channel that is closed on the next notify call.
+func (n *decisionNotifier) wait() <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.ch
+}
+
+// notify wakes up all current waiters.
+func (n *decisionNotifier) notify() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	close(n.ch)
+	n.ch = make(chan struct{})
+}
+
+// --- Access Requests ---
+
+// RequestAccess creates an unblock request. It is used by the agent after an app was killed;
+// the extension sends its requests over native messaging instead.
+func (s *Server) RequestAccess(kind, target, reason string) (int64, error) {
+	if kind == repository.AccessKindApp {
+		target = strings.ToLower(target)
+	}
+	return s.Access.CreateRequest(kind, target, "", reason)
+}
+
+// GetAccessRequests lists access requests with the given status ("pending", "approved", "denied" or "" for all).
+func (s *Server) GetAccessRequests(status string) ([]repository.AccessRequest, error) {
+	return s.Access.ListRequests(status)
+}
+
+// ApproveAccessRequest unblocks the requested target for durationMinutes.
+func (s *Server) ApproveAccessRequest(id int64, password string, durationMinutes int) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if durationMinutes <= 0 {
+		return fmt.Errorf("approval duration must be positive")
+	}
+
+	expiresAt := time.Now().Add(time.Duration(durationMinutes) * time.Minute).Unix()
+	if err := s.Access.Decide(id, repository.AccessStatusApproved, expiresAt); err != nil {
+		return err
+	}
+	s.Logger.Printf("[Access] Approved request %d for %d minutes", id, durationMinutes)
+	s.decisions.notify()
+	return nil
+}
+
+// DenyAccessRequest rejects a pending request.
+func (s *Server) DenyAccessRequest(id int64, password string) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+
+	if err := s.Access.Decide(id, repository.AccessStatusDenied, 0); err != nil {
+		return err
+	}
+	s.Logger.Printf("[Access] Denied request %d", id)
+	s.decisions.notify()
+	return nil
+}
+
+// WaitAccessDecisions returns the requests decided after a cursor: the decision time and ID of the
+// last decision received (see AccessRepository.GetDecisionsSince).
+// If there are none yet, it blocks until a decision is made or the timeout elapses,
+// which lets the agent receive outcomes without polling the database itself.
+func (s *Server) WaitAccessDecisions(since, afterID int64, timeoutSeconds int) ([]repository.AccessRequest, error) {
+	timeout := time.Duration(timeoutSeconds) * time.Second
+	if timeout <= 0 || timeout > maxDecisionWait {
+		timeout = maxDecisionWait
+	}
+
+	// Grab the wake-up channel before querying so a decision made in between isn't missed.
+	wake := s.decisions.wait()
+	decisions, err := s.Access.GetDecisionsSince(since, afterID)
+	if err != nil || len(decisions) > 0 {
+		return decisions, err
+	}
+
+	select {
+	case <-wake:
+	case <-time.After(timeout):
+	}
+	return s.Access.GetDecisionsSince(since, afterID)
+}
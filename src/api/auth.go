@@ -71,3 +71,15 @@ func (s *Server) SetPassword(password string) error {
 	s.Mu.Unlock()
 	return nil
 }
+
+// verifyPassword returns an error unless password matches the stored password hash.
+func verifyPassword(password string) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if !auth.CheckPasswordHash(password, cfg.PasswordHash) {
+		return fmt.Errorf("invalid password")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"veda-anchor-engine/src/internal/appgroup"
+	"veda-anchor-engine/src/internal/calendar"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/backup"
+	"veda-anchor-engine/src/internal/monitoring"
+	"veda-anchor-engine/src/internal/windowtitle"
+)
+
+// --- Backup & Restore ---
+
+// CreateBackup writes a backup archive of the engine state to path, or to the backup directory if path is empty.
+// It returns the path of the archive. It requires the password because the archive holds the whole
+// history and the settings, password hash included.
+func (s *Server) CreateBackup(path, password string) (string, error) {
+	if err := verifyPassword(password); err != nil {
+		return "", err
+	}
+	if path == "" {
+		var err error
+		if path, err = backup.DefaultPath("veda-anchor-backup"); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := backup.Create(s.db, path); err != nil {
+		return "", err
+	}
+	s.Logger.Printf("[Backup] Created backup %s", path)
+	return path, nil
+}
+
+// ListBackups lists the backup archives in the backup directory, newest first.
+func (s *Server) ListBackups() ([]backup.Info, error) {
+	return backup.List()
+}
+
+// RestoreBackup replaces the engine state with the content of a backup archive.
+// The current state is backed up first.
+func (s *Server) RestoreBackup(path, password string) (*backup.Manifest, error) {
+	if err := verifyPassword(password); err != nil {
+		return nil, err
+	}
+
+	manifest, err := backup.Restore(s.db, path)
+	if err != nil {
+		return nil, err
+	}
+	s.Logger.Printf("[Backup] Restored backup %s (engine %s, created %s)", path, manifest.EngineVersion, manifest.CreatedAt)
+
+	// The restored settings may hold a different password, and the open sessions no longer match the database.
+	s.Logout()
+	monitoring.ResetGlobalManager()
+	calendar.Reload()
+	windowtitle.Reload()
+	appgroup.Reload()
+	return manifest, nil
+}
+
+func (s *Server) GetBackupSettings() (config.BackupSettings, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.BackupSettings{}, err
+	}
+	return cfg.Backup, nil
+}
+
+// SetBackupSettings updates the automatic backups. It requires the password because backups hold the
+// whole history and the settings, password hash included.
+func (s *Server) SetBackupSettings(password string, settings config.BackupSettings) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if settings.IntervalHours < 0 || settings.Keep < 0 {
+		return fmt.Errorf("backup values must not be negative")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Backup = settings
+	return cfg.Save()
+}
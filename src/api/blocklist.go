@@ -61,6 +61,32 @@ func (s *Server) GetAppBlocklist() ([]app.BlockedAppDetail, error) {
 	return details, nil
 }
 
+func (s *Server) GetAppRules() ([]app.AppRule, error) {
+	return app.LoadAppRules()
+}
+
+func (s *Server) SetAppRule(name, scope string) error {
+	sc, err := app.ParseScope(scope)
+	if err != nil {
+		return err
+	}
+	return app.SetAppRule(app.AppRule{Name: name, Scope: sc})
+}
+
+// SetAppGroupRule blocks an application group, identified by its ID, with the given scope.
+func (s *Server) SetAppGroupRule(groupID, scope string) error {
+	sc, err := app.ParseScope(scope)
+	if err != nil {
+		return err
+	}
+	return app.SetAppRule(app.AppRule{Name: groupID, Scope: sc, Group: true})
+}
+
+func (s *Server) RemoveAppRule(name string) error {
+	_, err := app.RemoveAppRule(name)
+	return err
+}
+
 func (s *Server) ClearAppBlocklist() error {
 	return app.ClearAppBlocklist()
 }
@@ -70,9 +96,14 @@ func (s *Server) SaveAppBlocklist() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	rules, err := app.LoadAppRules()
+	if err != nil {
+		return nil, err
+	}
 	header := map[string]interface{}{
 		"exported_at": time.Now().Format(time.RFC3339),
 		"blocked":     list,
+		"rules":       rules,
 	}
 	return json.MarshalIndent(header, "", "  ")
 }
@@ -80,7 +111,8 @@ func (s *Server) SaveAppBlocklist() ([]byte, error) {
 func (s *Server) LoadAppBlocklist(content []byte) error {
 	var newEntries []string
 	var savedList struct {
-		Blocked []string `json:"blocked"`
+		Blocked []string      `json:"blocked"`
+		Rules   []app.AppRule `json:"rules"`
 	}
 	if err := json.Unmarshal(content, &newEntries); err != nil {
 		if err2 := json.Unmarshal(content, &savedList); err2 != nil {
@@ -88,6 +120,13 @@ func (s *Server) LoadAppBlocklist(content []byte) error {
 		}
 		newEntries = savedList.Blocked
 	}
+	if len(savedList.Rules) > 0 {
+		existingRules, err := app.LoadAppRules()
+		if err != nil {
+			return err
+		}
+		return app.SaveAppRules(append(existingRules, savedList.Rules...))
+	}
 	existingList, err := app.LoadAppBlocklist()
 	if err != nil {
 		return err
@@ -103,22 +142,29 @@ func (s *Server) LoadAppBlocklist(content []byte) error {
 // --- Web Blocklist ---
 
 func (s *Server) GetWebBlocklist() ([]web.BlockedWebsiteDetail, error) {
-	domains, err := web.LoadWebBlocklist()
+	rules, err := web.LoadWebRules()
 	if err != nil {
 		return nil, err
 	}
 
+	domains := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		domains = append(domains, rule.Domain)
+	}
+
 	records, err := s.Web.GetBlockedDetails(domains)
 	if err != nil {
 		return nil, err
 	}
 
 	details := make([]web.BlockedWebsiteDetail, 0, len(records))
-	for _, r := range records {
+	for i, r := range records {
 		details = append(details, web.BlockedWebsiteDetail{
-			Domain:  r.Domain,
-			Title:   r.Title,
-			IconURL: r.IconURL,
+			Domain:       r.Domain,
+			Title:        r.Title,
+			IconURL:      r.IconURL,
+			Action:       rules[i].Action,
+			DelaySeconds: rules[i].DelaySeconds,
 		})
 	}
 	return details, nil
@@ -134,6 +180,23 @@ func (s *Server) RemoveWebBlocklist(domain string) error {
 	return err
 }
 
+func (s *Server) GetWebRules() ([]web.WebRule, error) {
+	return web.LoadWebRules()
+}
+
+func (s *Server) SetWebRule(domain, action string, delaySeconds int) error {
+	a, err := web.ParseAction(action)
+	if err != nil {
+		return err
+	}
+	return web.SetWebRule(web.WebRule{Domain: domain, Action: a, DelaySeconds: delaySeconds})
+}
+
+func (s *Server) RemoveWebRule(domain string) error {
+	_, err := web.RemoveWebRule(domain)
+	return err
+}
+
 func (s *Server) ClearWebBlocklist() error {
 	return web.ClearWebBlocklist()
 }
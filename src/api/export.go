@@ -0,0 +1,121 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"veda-anchor-engine/src/internal/data/export"
+	"veda-anchor-engine/src/internal/data/importer"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+const (
+	// defaultExportChunkRows is the chunk size used when the caller doesn't ask for one.
+	defaultExportChunkRows = 5000
+	// maxExportChunkRows bounds the size of a single IPC response.
+	maxExportChunkRows = 50000
+)
+
+// ExportChunk is one part of a chunked export.
+type ExportChunk struct {
+	Data string `json:"data"`
+	Rows int64  `json:"rows"`
+	// Cursor is passed to the next ExportChunk call to continue the export.
+	Cursor int64 `json:"cursor"`
+	Done   bool  `json:"done"`
+}
+
+// --- Export ---
+
+// GetExportDatasets lists the exportable datasets and their column schemas.
+func (s *Server) GetExportDatasets() []export.Dataset {
+	return export.Datasets()
+}
+
+// ExportToFile streams a dataset for a time range to a file and returns the number of exported rows.
+// It requires the password because the service can write the history anywhere on the machine.
+func (s *Server) ExportToFile(dataset, format, since, until, path, password string) (int64, error) {
+	if err := verifyPassword(password); err != nil {
+		return 0, err
+	}
+	opts, err := exportOptions(dataset, format, since, until)
+	if err != nil {
+		return 0, err
+	}
+	if path == "" {
+		return 0, fmt.Errorf("no export path given")
+	}
+	opts.Header = true
+
+	// Write next to the target first, so a failed export never leaves a truncated file behind.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".export-*")
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	res, err := export.Write(s.db, tmp, opts)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	s.Logger.Printf("[Export] Exported %d %s rows to %s", res.Rows, dataset, path)
+	return res.Rows, nil
+}
+
+// ExportChunk returns up to maxRows rows of a dataset, starting after cursor (0 for the first chunk).
+// The CSV header is only included in the first chunk, so chunks can be concatenated as they are.
+func (s *Server) ExportChunk(dataset, format, since, until string, cursor int64, maxRows int) (*ExportChunk, error) {
+	opts, err := exportOptions(dataset, format, since, until)
+	if err != nil {
+		return nil, err
+	}
+	if maxRows <= 0 {
+		maxRows = defaultExportChunkRows
+	}
+	opts.Limit = min(maxRows, maxExportChunkRows)
+	opts.AfterID = cursor
+	opts.Header = cursor == 0
+
+	var buf bytes.Buffer
+	res, err := export.Write(s.db, &buf, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ExportChunk{
+		Data:   buf.String(),
+		Rows:   res.Rows,
+		Cursor: res.LastID,
+		Done:   res.Rows < int64(opts.Limit),
+	}, nil
+}
+
+// exportOptions parses the common export parameters.
+func exportOptions(dataset, format, since, until string) (export.Options, error) {
+	opts := export.Options{Dataset: dataset, Format: format}
+	var err error
+	opts.Since, opts.Until, err = repository.ParseTimeRange(since, until)
+	return opts, err
+}
+
+// --- Import ---
+
+// StartHistoryImport imports a copy of a Chromium "History" or Firefox "places.sqlite" file in the background.
+// browser labels the imported visits; if empty, it is detected from the file.
+func (s *Server) StartHistoryImport(path, browser string) error {
+	if path == "" {
+		return fmt.Errorf("no history file given")
+	}
+	return importer.Start(s.db, s.Logger, path, browser)
+}
+
+// GetHistoryImportStatus reports the progress and counts of the current or last import.
+func (s *Server) GetHistoryImportStatus() importer.Status {
+	return importer.GetStatus()
+}
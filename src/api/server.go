@@ -2,10 +2,13 @@ package api
 
 import (
 	"database/sql"
+	"fmt"
 	"sync"
+	"veda-anchor-engine/src/internal/appgroup"
 	"veda-anchor-engine/src/internal/data/logger"
 	"veda-anchor-engine/src/internal/data/repository"
 	"veda-anchor-engine/src/internal/platform/nativehost"
+	"veda-anchor-engine/src/internal/service/catalog"
 	"veda-anchor-engine/src/internal/service/icon"
 )
 
@@ -18,17 +21,21 @@ type Server struct {
 	icons           *icon.Service
 	Apps            *repository.AppRepository
 	Web             *repository.WebRepository
+	Access          *repository.AccessRepository
+	decisions       *decisionNotifier
 }
 
 // NewServer creates a new Server with its dependencies.
 func NewServer(db *sql.DB) *Server {
 	l := logger.GetLogger()
 	return &Server{
-		Logger: l,
-		db:     db,
-		icons:  icon.NewService(l),
-		Apps:   repository.NewAppRepository(db),
-		Web:    repository.NewWebRepository(db),
+		Logger:    l,
+		db:        db,
+		icons:     icon.NewService(l),
+		Apps:      repository.NewAppRepository(db),
+		Web:       repository.NewWebRepository(db),
+		Access:    repository.NewAccessRepository(db),
+		decisions: newDecisionNotifier(),
 	}
 }
 
@@ -47,6 +54,37 @@ func (s *Server) GetAppDetails(exePath string) (AppDetailsResponse, error) {
 	}, nil
 }
 
+// GetExecutableInfo returns the catalog entry of an executable: its hash, signer and version info.
+// An executable that isn't cataloged yet, or that changed since, is inspected now.
+func (s *Server) GetExecutableInfo(exePath string) (*repository.Executable, error) {
+	if exePath == "" {
+		return nil, fmt.Errorf("executable path is required")
+	}
+	e, err := catalog.Catalog(s.Apps, exePath)
+	if err == nil {
+		return e, nil
+	}
+	// The file may be gone; its last cataloged version is still known.
+	if known, lookupErr := s.Apps.GetExecutable(exePath); lookupErr == nil && known != nil {
+		return known, nil
+	}
+	return nil, err
+}
+
+// ListExecutables returns a page of the executables catalog, most recently seen first.
+// cursor is the nextCursor of the previous page.
+func (s *Server) ListExecutables(queryStr, cursor string, limit int, order string) (*repository.ExecutablePage, error) {
+	return s.Apps.ListExecutablesPage(queryStr, repository.PageRequest{Cursor: cursor, Limit: limit, Order: order})
+}
+
+// GetAppGroup returns the application group an executable belongs to.
+func (s *Server) GetAppGroup(exePath string) (appgroup.Group, error) {
+	if exePath == "" {
+		return appgroup.Group{}, fmt.Errorf("executable path is required")
+	}
+	return appgroup.Current().Resolve(s.Apps, exePath), nil
+}
+
 // GetWebDetails retrieves metadata for a given domain.
 func (s *Server) GetWebDetails(domain string) (repository.WebMetadata, error) {
 	meta, err := s.Web.GetMetadata(domain)
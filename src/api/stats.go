@@ -1,9 +1,23 @@
 package api
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 	"time"
+	"veda-anchor-engine/src/internal/appgroup"
+	"veda-anchor-engine/src/internal/calendar"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/filter"
 	"veda-anchor-engine/src/internal/data/repository"
+	"veda-anchor-engine/src/internal/data/rollup"
+	"veda-anchor-engine/src/internal/windowtitle"
+)
+
+// Result limits of SearchAll.
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
 )
 
 // --- Types ---
@@ -31,11 +45,28 @@ type ScreenTimeItem struct {
 	DurationSeconds int    `json:"durationSeconds"`
 }
 
+// AppGroupItem is the usage of an application group.
+type AppGroupItem struct {
+	Rank int    `json:"rank"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Icon string `json:"icon"`
+	// Count is the number of launches of the group's executables.
+	Count           int      `json:"count"`
+	DurationSeconds int      `json:"durationSeconds"`
+	Executables     []string `json:"executables"`
+}
+
+// maxGroupItems is the number of groups returned by the group leaderboards.
+const maxGroupItems = 10
+
 // --- App Usage ---
 
 func (s *Server) GetAppLeaderboard(since, until string) ([]AppLeaderboardItem, error) {
-	sinceTime, _ := repository.ParseTime(since)
-	untilTime, _ := repository.ParseTime(until)
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
 
 	records, err := s.Apps.GetUsageRanking(sinceTime, untilTime)
 	if err != nil {
@@ -61,11 +92,14 @@ func (s *Server) GetAppLeaderboard(since, until string) ([]AppLeaderboardItem, e
 	return leaderboard, nil
 }
 
-func (s *Server) GetScreenTime() ([]ScreenTimeItem, error) {
-	now := time.Now()
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
+// GetScreenTime returns the screen time per application in a time range. An empty since means today.
+func (s *Server) GetScreenTime(since, until string) ([]ScreenTimeItem, error) {
+	sinceTime, untilTime, err := screenTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
 
-	records, err := s.Apps.GetScreenTimeTotals(todayStart)
+	records, err := s.Apps.GetScreenTimeTotals(sinceTime, untilTime)
 	if err != nil {
 		return nil, err
 	}
@@ -88,17 +122,181 @@ func (s *Server) GetScreenTime() ([]ScreenTimeItem, error) {
 	return items, nil
 }
 
-func (s *Server) GetTotalScreenTime() (int, error) {
-	now := time.Now()
-	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).Unix()
-	return s.Apps.GetTotalDayScreenTime(todayStart)
+// GetAppGroupLeaderboard returns the most launched application groups in a time range.
+func (s *Server) GetAppGroupLeaderboard(since, until string) ([]AppGroupItem, error) {
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.appGroupUsage(sinceTime, untilTime)
+	if err != nil {
+		return nil, err
+	}
+	return rankAppGroups(items, func(item AppGroupItem) int { return item.Count }), nil
+}
+
+// GetAppGroupScreenTime returns the screen time per application group in a time range. An empty since means today.
+func (s *Server) GetAppGroupScreenTime(since, until string) ([]AppGroupItem, error) {
+	sinceTime, untilTime, err := screenTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.appGroupUsage(sinceTime, untilTime)
+	if err != nil {
+		return nil, err
+	}
+	return rankAppGroups(items, func(item AppGroupItem) int { return item.DurationSeconds }), nil
+}
+
+// appGroupUsage sums the usage of the executables in a time range per application group.
+// The icon is that of the group's most used executable.
+func (s *Server) appGroupUsage(sinceTime, untilTime time.Time) ([]AppGroupItem, error) {
+	usage, err := s.Apps.GetUsageByPath(sinceTime, untilTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// Most used first, so the first executable of each group provides its icon.
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].ForegroundSeconds != usage[j].ForegroundSeconds {
+			return usage[i].ForegroundSeconds > usage[j].ForegroundSeconds
+		}
+		return usage[i].Launches > usage[j].Launches
+	})
+
+	resolver := appgroup.Current()
+	byID := make(map[string]*AppGroupItem)
+	var items []*AppGroupItem
+	for _, u := range usage {
+		group := resolver.Resolve(s.Apps, u.ExecutablePath)
+		item, ok := byID[group.ID]
+		if !ok {
+			details := s.icons.GetAppDetails(u.ExecutablePath)
+			name := group.Name
+			if strings.HasPrefix(group.ID, "path:") && details.CommercialName != "" {
+				name = details.CommercialName
+			}
+			item = &AppGroupItem{ID: group.ID, Name: name, Icon: details.IconBase64}
+			byID[group.ID] = item
+			items = append(items, item)
+		}
+		item.Count += u.Launches
+		item.DurationSeconds += u.ForegroundSeconds
+		item.Executables = append(item.Executables, u.ExecutablePath)
+	}
+
+	result := make([]AppGroupItem, 0, len(items))
+	for _, item := range items {
+		result = append(result, *item)
+	}
+	return result, nil
+}
+
+// rankAppGroups returns the groups with the highest non-zero value, ranked.
+func rankAppGroups(items []AppGroupItem, value func(AppGroupItem) int) []AppGroupItem {
+	sort.SliceStable(items, func(i, j int) bool { return value(items[i]) > value(items[j]) })
+	ranked := make([]AppGroupItem, 0, min(len(items), maxGroupItems))
+	for _, item := range items {
+		if value(item) == 0 || len(ranked) == maxGroupItems {
+			break
+		}
+		item.Rank = len(ranked) + 1
+		ranked = append(ranked, item)
+	}
+	return ranked
+}
+
+// GetTotalScreenTime returns the total screen time in a time range. An empty since means today.
+func (s *Server) GetTotalScreenTime(since, until string) (int, error) {
+	sinceTime, untilTime, err := screenTimeRange(since, until)
+	if err != nil {
+		return 0, err
+	}
+	return s.Apps.GetTotalScreenTime(sinceTime, untilTime)
+}
+
+// GetUsageHeatmap returns the foreground seconds and web visits between since and until by day of week
+// and hour of day. app (an executable path or process name) restricts it to an application, in which case
+// there are no visits; domain restricts it to a domain, in which case there is no foreground time.
+// An empty since means all recorded history and an empty until means now.
+func (s *Server) GetUsageHeatmap(since, until, app, domain string) (*repository.UsageHeatmap, error) {
+	if app != "" && domain != "" {
+		return nil, fmt.Errorf("filter by an app or a domain, not both")
+	}
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+
+	var seconds, visits *repository.Heatmap
+	if domain == "" {
+		seconds, err = s.Apps.GetForegroundHeatmap(sinceTime, untilTime, strings.TrimSpace(app))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if app == "" {
+		visits, err = s.Web.GetVisitHeatmap(sinceTime, untilTime, strings.TrimSpace(domain))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return repository.NewUsageHeatmap(calendar.Current().WeekStart(), seconds, visits), nil
+}
+
+// GetScreenTimeSeries returns the screen time between since and until, bucketed by unit
+// ("hour", "day", "week" or "month"). An empty until means now.
+func (s *Server) GetScreenTimeSeries(unit, since, until string) (*repository.ScreenTimeSeries, error) {
+	if since == "" {
+		return nil, fmt.Errorf("a start time is required")
+	}
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	if untilTime.IsZero() {
+		untilTime = time.Now()
+	}
+
+	series, err := s.Apps.GetScreenTimeSeries(unit, sinceTime, untilTime)
+	if err != nil {
+		return nil, err
+	}
+	for i := range series.Apps {
+		app := &series.Apps[i]
+		app.Name = s.icons.GetAppDetails(app.ExecutablePath).CommercialName
+		if app.Name == "" {
+			app.Name = app.ProcessName
+		}
+	}
+	return series, nil
+}
+
+// GetSystemGaps returns the periods without data between since and until: the machine was suspended
+// or the wall clock jumped. An empty since means today.
+func (s *Server) GetSystemGaps(since, until string) ([]repository.SystemGap, error) {
+	sinceTime, untilTime, err := screenTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	return s.Apps.GetSystemGaps(sinceTime, untilTime)
+}
+
+// screenTimeRange parses the range of a screen time query, defaulting to the start of today.
+func screenTimeRange(since, until string) (time.Time, time.Time, error) {
+	if since == "" {
+		since = "today"
+	}
+	return repository.ParseTimeRange(since, until)
 }
 
 // --- Web Usage ---
 
 func (s *Server) GetWebLeaderboard(since, until string) ([]WebLeaderboardItem, error) {
-	sinceTime, _ := repository.ParseTime(since)
-	untilTime, _ := repository.ParseTime(until)
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
 
 	records, err := s.Web.GetUsageRanking(sinceTime, untilTime)
 	if err != nil {
@@ -121,6 +319,24 @@ func (s *Server) GetWebLeaderboard(since, until string) ([]WebLeaderboardItem, e
 	return leaderboard, nil
 }
 
+func (s *Server) GetFrictionStats(since, until string) ([]repository.FrictionStat, error) {
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	return s.Web.GetFrictionStats(sinceTime, untilTime)
+}
+
+// RebuildRollups recomputes the usage rollups from the raw event tables that are still retained.
+func (s *Server) RebuildRollups() error {
+	start := time.Now()
+	if err := rollup.Rebuild(s.db); err != nil {
+		return fmt.Errorf("could not rebuild rollups: %w", err)
+	}
+	s.Logger.Printf("[Rollup] Rebuilt rollups in %v", time.Since(start))
+	return nil
+}
+
 // --- Logs & Search ---
 
 func (s *Server) Search(queryStr, since, until string) ([][]string, error) {
@@ -131,6 +347,52 @@ func (s *Server) GetWebLogs(queryStr, since, until string) ([][]string, error) {
 	return s.Web.GetLogs(queryStr, since, until)
 }
 
+// SearchPage returns a page of application events. cursor is the nextCursor of the previous page.
+func (s *Server) SearchPage(queryStr, since, until, cursor string, limit int, order string) (*repository.AppEventPage, error) {
+	return s.Apps.SearchEventsPage(strings.ToLower(queryStr), since, until, repository.PageRequest{Cursor: cursor, Limit: limit, Order: order})
+}
+
+// GetProcessAncestry returns the process of an application event and its known ancestors, starting
+// with the process itself, e.g. game.exe, steam.exe, explorer.exe.
+func (s *Server) GetProcessAncestry(eventID int64) ([]repository.ProcessAncestor, error) {
+	return s.Apps.GetProcessAncestry(eventID)
+}
+
+// GetWebLogsPage returns a page of web logs. cursor is the nextCursor of the previous page.
+func (s *Server) GetWebLogsPage(queryStr, since, until, cursor string, limit int, order string) (*repository.WebLogPage, error) {
+	return s.Web.GetLogsPage(queryStr, since, until, repository.PageRequest{Cursor: cursor, Limit: limit, Order: order})
+}
+
+// SearchAll runs a full-text search over application and web events and returns the best hits of both,
+// ranked together (see repository.MergeHits). limit defaults to 50.
+func (s *Server) SearchAll(query, since, until string, limit int) ([]repository.SearchHit, error) {
+	sinceTime, untilTime, err := repository.ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	limit = min(limit, maxSearchLimit)
+
+	appHits, err := s.Apps.SearchText(query, sinceTime, untilTime, limit)
+	if err != nil {
+		return nil, err
+	}
+	webHits, err := s.Web.SearchText(query, sinceTime, untilTime, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return repository.MergeHits(limit, appHits, webHits), nil
+}
+
+// FilterHistory runs a filter query such as "app:chrome.exe after:2026-10-01 -domain:google.com"
+// over app, screen time and web events. Syntax errors are returned as *filter.SyntaxError.
+func (s *Server) FilterHistory(query string, limit int) ([]filter.Result, error) {
+	return filter.Search(s.db, query, limit)
+}
+
 // --- Utils ---
 
 func extractFileName(path string) string {
@@ -144,10 +406,81 @@ func extractFileName(path string) string {
 
 // --- Agent Communication ---
 
-func (s *Server) UpdateScreenTime(pid uint32, seconds int64) error {
-	return s.Apps.UpdateScreenTimeByPID(pid, seconds)
+// ReportForegroundStart records that a process came to the foreground. key is its process instance
+// key (PID-StartTime), which may be empty. windowTitle is the title of the foreground window; it is
+// only kept if title capture is enabled, after the title rules are applied.
+func (s *Server) ReportForegroundStart(pid uint32, exePath, key, windowTitle string) error {
+	return s.Apps.StartForeground(pid, exePath, key, windowtitle.Current().Normalize(windowTitle), time.Now())
+}
+
+// ReportForegroundStop records that a process left the foreground. A pid of 0 stands for any process.
+func (s *Server) ReportForegroundStop(pid uint32) error {
+	return s.Apps.StopForeground(pid, time.Now())
+}
+
+// UpdateScreenTime confirms that a process is still in the foreground. The time since the previous
+// report counts as foreground time, unless the user is idle (see applyIdleState).
+func (s *Server) UpdateScreenTime(pid uint32, idle bool, idleSeconds int64) error {
+	now := time.Now()
+	if isIdle, err := s.applyIdleState(pid, idle, idleSeconds, now); isIdle || err != nil {
+		return err
+	}
+	return s.Apps.ConfirmForeground(pid, now)
+}
+
+// ReportActiveApp reports the process in the foreground, for agents that don't report transitions.
+// While the user is idle, the process doesn't get any foreground time.
+func (s *Server) ReportActiveApp(pid uint32, exePath, windowTitle string, idle bool, idleSeconds int64) error {
+	now := time.Now()
+	if isIdle, err := s.applyIdleState(pid, idle, idleSeconds, now); isIdle || err != nil {
+		return err
+	}
+	return s.Apps.StartForeground(pid, exePath, "", windowtitle.Current().Normalize(windowTitle), now)
+}
+
+// applyIdleState records the idle state reported by the agent and reports whether the user is idle.
+// The user is idle when the agent says so, or when there was no input for the configured threshold.
+// Agents that don't report an idle state leave the user active.
+func (s *Server) applyIdleState(pid uint32, idle bool, idleSeconds int64, now time.Time) (bool, error) {
+	threshold := config.DefaultIdle().ThresholdSeconds
+	if cfg, err := config.LoadConfig(); err == nil {
+		threshold = cfg.Idle.ThresholdSeconds
+	}
+	if threshold <= 0 {
+		idle = false
+	} else if idleSeconds >= int64(threshold) {
+		idle = true
+	}
+
+	if idle {
+		lastInput := now.Add(-time.Duration(max(idleSeconds, 0)) * time.Second)
+		return true, s.Apps.SetIdle(lastInput, now)
+	}
+	return false, s.Apps.SetActive(pid, now)
 }
 
-func (s *Server) ReportActiveApp(pid uint32, exePath string) error {
-	return s.Apps.ReportActiveApp(pid, exePath)
+// GetAppTitleBreakdown returns the screen time of an application between since and until per window title.
+// An empty since means today.
+func (s *Server) GetAppTitleBreakdown(exePath, since, until string) ([]repository.TitleUsage, error) {
+	if exePath == "" {
+		return nil, fmt.Errorf("executable path is required")
+	}
+	sinceTime, untilTime, err := screenTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	return s.Apps.GetAppTitleBreakdown(exePath, sinceTime, untilTime)
+}
+
+// GetIdleTime returns the idle time of each day between since and until. An empty since means today
+// and an empty until means now.
+func (s *Server) GetIdleTime(since, until string) ([]repository.DailyIdle, error) {
+	sinceTime, untilTime, err := screenTimeRange(since, until)
+	if err != nil {
+		return nil, err
+	}
+	if untilTime.IsZero() {
+		untilTime = time.Now()
+	}
+	return s.Apps.GetDailyIdleTime(sinceTime, untilTime)
 }
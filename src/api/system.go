@@ -5,16 +5,23 @@ import (
 	"os"
 	"strings"
 	"time"
+	"veda-anchor-engine/src/internal/appgroup"
 	"veda-anchor-engine/src/internal/auth"
 	app_blocklist "veda-anchor-engine/src/internal/blocklist/app"
+	"veda-anchor-engine/src/internal/calendar"
 	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data"
 	"veda-anchor-engine/src/internal/data/history"
+	"veda-anchor-engine/src/internal/data/retention"
+	"veda-anchor-engine/src/internal/data/spool"
+	"veda-anchor-engine/src/internal/data/write"
 	"veda-anchor-engine/src/internal/monitoring"
 	"veda-anchor-engine/src/internal/platform/autostart"
 	"veda-anchor-engine/src/internal/platform/nativehost"
 	"veda-anchor-engine/src/internal/platform/proc_sensing"
 	"veda-anchor-engine/src/internal/platform/uninstall"
 	"veda-anchor-engine/src/internal/web/native_messaging"
+	"veda-anchor-engine/src/internal/windowtitle"
 )
 
 const appName = "Veda"
@@ -27,6 +34,9 @@ func (s *Server) Shutdown() {
 
 	go func() {
 		time.Sleep(1 * time.Second)
+		if err := data.FlushWrites(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to flush database writes: %v\n", err)
+		}
 		s.Logger.Close()
 		if err := s.db.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to close database: %v\n", err)
@@ -45,6 +55,9 @@ func (s *Server) Uninstall(password string) error {
 	}
 
 	go func() {
+		if err := data.FlushWrites(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to flush database writes: %v\n", err)
+		}
 		s.Logger.Close()
 		if err := s.db.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to close database: %v\n", err)
@@ -110,6 +123,176 @@ func (s *Server) ClearWebHistory(password string) error {
 	return nil
 }
 
+// GetWriterStats returns the state of the database write queue, for diagnostics.
+func (s *Server) GetWriterStats() write.Stats {
+	return write.GetStats()
+}
+
+// GetSpoolStatus returns the size of the on-disk write spool and the outcome of replays.
+func (s *Server) GetSpoolStatus() (spool.Status, error) {
+	return spool.GetStatus()
+}
+
+func (s *Server) GetRetentionSettings() (config.RetentionSettings, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.RetentionSettings{}, err
+	}
+	return cfg.Retention, nil
+}
+
+// SetRetentionSettings updates the retention policy. It requires the password because
+// shortening the retention deletes history.
+func (s *Server) SetRetentionSettings(password string, settings config.RetentionSettings) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if settings.AppEventsDays < 0 || settings.WebEventsDays < 0 || settings.ScreenTimeDays < 0 ||
+		settings.LogsDays < 0 || settings.MaxDatabaseMB < 0 {
+		return fmt.Errorf("retention values must not be negative")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Retention = settings
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	retention.TriggerPrune()
+	return nil
+}
+
+func (s *Server) GetCalendarSettings() (config.CalendarSettings, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.CalendarSettings{}, err
+	}
+	return cfg.Calendar, nil
+}
+
+// SetCalendarSettings updates the calendar used for daily statistics and relative time ranges such as
+// "this week". A new timezone or day start moves the day boundaries, so the rollups are rebuilt.
+// It requires the password because moving the day boundaries shifts daily screen time and reports.
+func (s *Server) SetCalendarSettings(password string, settings config.CalendarSettings) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if err := calendar.Validate(settings); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	previous := cfg.Calendar
+	cfg.Calendar = settings
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+
+	calendar.Reload()
+	if settings.Timezone != previous.Timezone || settings.DayStartHour != previous.DayStartHour {
+		// Let queued rollup updates land first so the rebuild recomputes them too.
+		if err := data.FlushWrites(); err != nil {
+			s.Logger.Printf("[Calendar] Failed to flush writes before rebuilding rollups: %v", err)
+		}
+		return s.RebuildRollups()
+	}
+	return nil
+}
+
+func (s *Server) GetIdleSettings() (config.IdleSettings, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.IdleSettings{}, err
+	}
+	return cfg.Idle, nil
+}
+
+// SetIdleSettings updates when the user counts as idle. It requires the password because a shorter
+// threshold hides screen time.
+func (s *Server) SetIdleSettings(password string, settings config.IdleSettings) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if settings.ThresholdSeconds < 0 {
+		return fmt.Errorf("idle threshold must not be negative")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Idle = settings
+	return cfg.Save()
+}
+
+// GetTitleSettings returns whether window titles are captured and the rules applied to them.
+func (s *Server) GetTitleSettings() (config.TitleSettings, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.TitleSettings{}, err
+	}
+	return cfg.Titles, nil
+}
+
+// SetTitleSettings updates window title capture. It requires the password because turning capture off
+// or redacting titles hides screen time details. The new rules apply to titles reported from now on.
+func (s *Server) SetTitleSettings(password string, settings config.TitleSettings) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if err := windowtitle.Validate(settings); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Titles = settings
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	windowtitle.Reload()
+	return nil
+}
+
+// GetAppGroupSettings returns the path rules and merges that executables are grouped by.
+func (s *Server) GetAppGroupSettings() (config.AppGroupSettings, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return config.AppGroupSettings{}, err
+	}
+	return cfg.AppGroups, nil
+}
+
+// SetAppGroupSettings updates how executables are grouped. It requires the password because moving an
+// executable out of a blocked group unblocks it. Statistics are regrouped at once.
+func (s *Server) SetAppGroupSettings(password string, settings config.AppGroupSettings) error {
+	if err := verifyPassword(password); err != nil {
+		return err
+	}
+	if err := appgroup.Validate(settings); err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	cfg.AppGroups = settings
+	if err := cfg.Save(); err != nil {
+		return err
+	}
+	appgroup.Reload()
+	return nil
+}
+
 // --- Internal Helpers ---
 
 func (s *Server) killOtherVedaProcesses() {
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"veda-anchor-engine/src/internal/auth"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data"
+	"veda-anchor-engine/src/internal/data/backup"
+	"veda-anchor-engine/src/internal/data/connection"
+	"veda-anchor-engine/src/internal/ipc"
+)
+
+// runCLI runs a maintenance command instead of the service and returns the process exit code.
+//
+// Usage:
+//
+//	veda-anchor-engine backup [-out <archive>]
+//	veda-anchor-engine restore -password <password> <archive>
+//	veda-anchor-engine version
+//
+// The service must be stopped before restoring, so that none of its writes land while the database
+// is swapped. To restore while it runs, use its RestoreBackup call, which pauses its writes.
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	switch args[0] {
+	case "backup":
+		fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		out := fs.String("out", "", "path of the backup archive (default: the backup directory)")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		return cliBackup(*out, stdout, stderr)
+
+	case "restore":
+		fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+		fs.SetOutput(stderr)
+		password := fs.String("password", "", "the engine password")
+		if err := fs.Parse(args[1:]); err != nil {
+			return 2
+		}
+		if fs.NArg() != 1 {
+			fmt.Fprintln(stderr, "usage: restore -password <password> <archive>")
+			return 2
+		}
+		return cliRestore(fs.Arg(0), *password, stdout, stderr)
+
+	case "version":
+		fmt.Fprintln(stdout, config.Version)
+		return 0
+
+	default:
+		fmt.Fprintf(stderr, "unknown command %q (expected backup, restore or version)\n", args[0])
+		return 2
+	}
+}
+
+func cliBackup(out string, stdout, stderr io.Writer) int {
+	db, err := data.InitDB()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	if out == "" {
+		if out, err = backup.DefaultPath("veda-anchor-backup"); err != nil {
+			fmt.Fprintf(stderr, "Failed to get backup directory: %v\n", err)
+			return 1
+		}
+	}
+	if _, err := backup.Create(db, out); err != nil {
+		fmt.Fprintf(stderr, "Backup failed: %v\n", err)
+		return 1
+	}
+	if err := data.FlushWrites(); err != nil {
+		fmt.Fprintf(stderr, "Failed to flush database writes: %v\n", err)
+	}
+	fmt.Fprintf(stdout, "Backup written to %s\n", out)
+	return 0
+}
+
+func cliRestore(archive, password string, stdout, stderr io.Writer) int {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to load settings: %v\n", err)
+		return 1
+	}
+	if !auth.CheckPasswordHash(password, cfg.PasswordHash) {
+		fmt.Fprintln(stderr, "Invalid password")
+		return 1
+	}
+
+	if ipc.IsServerRunning() {
+		fmt.Fprintln(stderr, "The engine service is running; stop it before restoring, or restore from the app")
+		return 1
+	}
+
+	// Open without migrating: the current database may be the very thing being repaired.
+	db, err := connection.OpenAndConfigureDB()
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer func() { _ = db.Close() }()
+
+	manifest, err := backup.Restore(db, archive)
+	if err != nil {
+		fmt.Fprintf(stderr, "Restore failed: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stdout, "Restored backup from %s (engine %s)\n", manifest.CreatedAt.Local().Format("2006-01-02 15:04"), manifest.EngineVersion)
+	return 0
+}
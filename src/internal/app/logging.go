@@ -1,7 +1,6 @@
 package app
 
 import (
-	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -9,6 +8,7 @@ import (
 	"veda-anchor-engine/src/internal/data/repository"
 	"veda-anchor-engine/src/internal/platform/app_filter"
 	"veda-anchor-engine/src/internal/platform/proc_sensing"
+	"veda-anchor-engine/src/internal/service/appname"
 )
 
 const processCheckInterval = 2 * time.Second
@@ -122,10 +122,14 @@ func logNewProcesses(state *loggerState, appLogger logger.Logger, procs []proc_s
 
 		// Log it
 		// Note: ShouldTrack is now handled by Agent - Engine logs all non-excluded processes
-		parentName := fmt.Sprintf("PID: %d", p.ParentPID)
+		parent := repository.ParentProcess{PID: p.ParentPID}
+		if found, ok := proc_sensing.FindParent(p, procs); ok {
+			parent.Name, parent.ExePath, parent.Key = found.Name, found.ExePath, found.UniqueKey()
+		}
 
 		// Store a standard Unix timestamp for display and the high-precision UniqueKey for process identity.
-		repo.LogAppEvent(name, p.PID, parentName, exePath, time.Now().Unix(), key)
+		repo.LogAppEvent(name, p.PID, parent, exePath, time.Now().Unix(), key)
+		appname.Enqueue(key, exePath)
 
 		state.runningProcs[key] = nameLower
 		state.runningAppCounts[nameLower]++
@@ -0,0 +1,279 @@
+package appgroup
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+// This package groups executables into logical applications: Chrome, Discord or Steam run many
+// executables, and auto-updating applications move to a new path on every version.
+//
+// DESIGN DECISION:
+// A group is derived, never stored: statistics and rules keep referring to executables and group
+// IDs, so changing the settings regroups all history at once. An executable belongs to, in order:
+//  1. the user-defined merge listing it, its file name or its group;
+//  2. the group of its publisher and product name, from the executables catalog, if its signature is valid;
+//  3. the group of its normalized path.
+//
+// Products shared by unrelated executables, such as the Windows components, are grouped by path, and
+// so are unsigned executables, which could claim any publisher. Resolved groups are cached; an
+// executable that isn't cataloged yet is looked up again after uncatalogedTTL, so it moves to its
+// product group once the catalog has inspected it, or as soon as the catalog reports it through
+// Cataloged. Files are never read here. Until then, PathGroups tells the groups of the executables
+// cataloged at the same normalized path, so a rule can hold an application that updated itself to a
+// new path. Reload must be called whenever the settings change.
+
+// uncatalogedTTL is how long the group of an executable that isn't in the catalog is cached.
+const uncatalogedTTL = time.Minute
+
+// sharedProducts are product names used by unrelated executables.
+var sharedProducts = []string{
+	"microsoft® windows® operating system",
+	"microsoft windows operating system",
+}
+
+// Group is a logical application.
+type Group struct {
+	// ID identifies the group: "merge:<name>", "product:<publisher>/<product>" or "path:<normalized path>".
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Resolver assigns executables to groups according to the grouping settings.
+type Resolver struct {
+	rules  []rule
+	merges []merge
+
+	mu    sync.Mutex
+	cache map[string]cachedGroup
+	// pathGroups maps normalized paths to the groups of the executables cataloged at them, once
+	// pathsLoaded is set.
+	pathGroups  map[string][]string
+	pathsLoaded bool
+}
+
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+type merge struct {
+	group   Group
+	members []string
+}
+
+type cachedGroup struct {
+	group Group
+	// expires is zero for executables found in the catalog.
+	expires time.Time
+}
+
+var (
+	mu      sync.Mutex
+	current *Resolver
+)
+
+// Current returns the resolver of the configured settings.
+// If the settings can't be read or are invalid, the default settings are used.
+func Current() *Resolver {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		r, _ := New(config.DefaultAppGroups())
+		if cfg, err := config.LoadConfig(); err == nil {
+			if configured, err := New(cfg.AppGroups); err == nil {
+				r = configured
+			}
+		}
+		current = r
+	}
+	return current
+}
+
+// Cataloged records that an executable was just cataloged, so it is grouped by its catalog entry
+// right away, even before the entry is written to the database.
+func Cataloged(exe repository.Executable) {
+	r := Current()
+	c := cachedGroup{group: r.Classify(exe.Path, &exe)}
+
+	r.mu.Lock()
+	r.cache[strings.ToLower(exe.Path)] = c
+	r.addPathGroup(exe.Path, c.group)
+	r.mu.Unlock()
+}
+
+// Reload makes the next Current read the settings again.
+func Reload() {
+	mu.Lock()
+	defer mu.Unlock()
+	current = nil
+}
+
+// New returns the resolver of the given settings.
+func New(settings config.AppGroupSettings) (*Resolver, error) {
+	r := &Resolver{cache: make(map[string]cachedGroup), pathGroups: make(map[string][]string)}
+	for i, pr := range settings.PathRules {
+		re, err := regexp.Compile(pr.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in path rule %d: %w", i+1, err)
+		}
+		r.rules = append(r.rules, rule{re: re, replacement: pr.Replacement})
+	}
+
+	seen := make(map[string]bool)
+	for i, m := range settings.Merges {
+		name := strings.TrimSpace(m.Name)
+		if name == "" {
+			return nil, fmt.Errorf("merge %d has no name", i+1)
+		}
+		id := "merge:" + strings.ToLower(name)
+		if seen[id] {
+			return nil, fmt.Errorf("duplicate merge name: %s", name)
+		}
+		seen[id] = true
+
+		mg := merge{group: Group{ID: id, Name: name}}
+		for _, member := range m.Members {
+			if member = strings.ToLower(strings.TrimSpace(member)); member != "" {
+				mg.members = append(mg.members, member)
+			}
+		}
+		if len(mg.members) == 0 {
+			return nil, fmt.Errorf("merge %s has no members", name)
+		}
+		r.merges = append(r.merges, mg)
+	}
+	return r, nil
+}
+
+// Validate checks grouping settings.
+func Validate(settings config.AppGroupSettings) error {
+	_, err := New(settings)
+	return err
+}
+
+// Resolve returns the group of an executable. repo provides the catalog; if it is nil, the executable
+// is grouped as if it weren't cataloged.
+func (r *Resolver) Resolve(repo *repository.AppRepository, exePath string) Group {
+	g, _ := r.Lookup(repo, exePath)
+	return g
+}
+
+// Lookup returns the group of an executable like Resolve, and whether the executable is cataloged.
+// An executable that isn't is grouped by path for now.
+func (r *Resolver) Lookup(repo *repository.AppRepository, exePath string) (Group, bool) {
+	key := strings.ToLower(exePath)
+	now := time.Now()
+
+	r.mu.Lock()
+	c, ok := r.cache[key]
+	r.mu.Unlock()
+	if ok && (c.expires.IsZero() || now.Before(c.expires)) {
+		return c.group, c.expires.IsZero()
+	}
+
+	var exe *repository.Executable
+	if repo != nil {
+		exe, _ = repo.GetExecutable(exePath)
+	}
+	if exe != nil {
+		c = cachedGroup{group: r.Classify(exePath, exe)}
+	} else {
+		c = cachedGroup{group: r.Classify(exePath, nil), expires: now.Add(uncatalogedTTL)}
+	}
+
+	r.mu.Lock()
+	r.cache[key] = c
+	r.mu.Unlock()
+	return c.group, exe != nil
+}
+
+// PathGroups returns the groups of the cataloged executables whose path normalizes like exePath, such
+// as the earlier versions of an application that moved to a new path when it updated. The path group
+// itself isn't included. The catalog is read from repo the first time.
+func (r *Resolver) PathGroups(repo *repository.AppRepository, exePath string) []string {
+	r.mu.Lock()
+	loaded := r.pathsLoaded
+	r.mu.Unlock()
+	if !loaded && repo != nil {
+		exes, err := repo.GetSignedExecutables()
+		if err == nil {
+			groups := make([]Group, len(exes))
+			for i := range exes {
+				groups[i] = r.Classify(exes[i].Path, &exes[i])
+			}
+			r.mu.Lock()
+			for i := range exes {
+				r.addPathGroup(exes[i].Path, groups[i])
+			}
+			r.pathsLoaded = true
+			r.mu.Unlock()
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.pathGroups[r.NormalizePath(exePath)])
+}
+
+// addPathGroup records the group of a cataloged executable for PathGroups. r.mu must be held.
+func (r *Resolver) addPathGroup(exePath string, g Group) {
+	normalized := r.NormalizePath(exePath)
+	if g.ID != "path:"+normalized && !slices.Contains(r.pathGroups[normalized], g.ID) {
+		r.pathGroups[normalized] = append(r.pathGroups[normalized], g.ID)
+	}
+}
+
+// Classify returns the group of an executable given its catalog entry, which may be nil.
+// The publisher and product name only count if the signature is valid.
+func (r *Resolver) Classify(exePath string, exe *repository.Executable) Group {
+	normalized := r.NormalizePath(exePath)
+
+	var g Group
+	if exe != nil && exe.SignatureValid && exe.Signer != "" && exe.ProductName != "" && !isSharedProduct(exe.ProductName) {
+		g = Group{
+			ID:   "product:" + strings.ToLower(exe.Signer) + "/" + strings.ToLower(exe.ProductName),
+			Name: exe.ProductName,
+		}
+	} else {
+		name := fileName(exePath)
+		if strings.HasSuffix(strings.ToLower(name), ".exe") {
+			name = name[:len(name)-len(".exe")]
+		}
+		g = Group{ID: "path:" + normalized, Name: name}
+	}
+
+	candidates := []string{g.ID, normalized, strings.ToLower(exePath), strings.ToLower(fileName(exePath))}
+	for _, m := range r.merges {
+		for _, member := range m.members {
+			if slices.Contains(candidates, member) {
+				return m.group
+			}
+		}
+	}
+	return g
+}
+
+// NormalizePath returns the lowercased executable path with the path rules applied.
+func (r *Resolver) NormalizePath(exePath string) string {
+	p := strings.ToLower(exePath)
+	for _, rl := range r.rules {
+		p = rl.re.ReplaceAllString(p, rl.replacement)
+	}
+	return p
+}
+
+func isSharedProduct(productName string) bool {
+	return slices.Contains(sharedProducts, strings.ToLower(productName))
+}
+
+// fileName returns the last element of a Windows or slash-separated path.
+func fileName(p string) string {
+	return p[strings.LastIndexAny(p, `\/`)+1:]
+}
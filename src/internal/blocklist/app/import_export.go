@@ -14,10 +14,15 @@ func ExportAppBlocklist(path string) error {
 	if err != nil {
 		return err
 	}
+	rules, err := LoadAppRules()
+	if err != nil {
+		return err
+	}
 
 	header := map[string]interface{}{
 		"exported_at": time.Now().Format(time.RFC3339),
 		"blocked":     list,
+		"rules":       rules,
 	}
 
 	b, err := json.MarshalIndent(header, "", "  ")
@@ -32,6 +37,7 @@ func ExportAppBlocklist(path string) error {
 }
 
 // ImportAppBlocklist loads a blocklist from a file and merges it with the existing blocklist.
+// The rules of a previously exported file are imported with their scopes.
 func ImportAppBlocklist(path string) error {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -40,7 +46,8 @@ func ImportAppBlocklist(path string) error {
 
 	var newEntries []string
 	var savedList struct {
-		Blocked []string `json:"blocked"`
+		Blocked []string  `json:"blocked"`
+		Rules   []AppRule `json:"rules"`
 	}
 
 	// The imported file can be a simple list of strings or a previously exported file.
@@ -53,6 +60,14 @@ func ImportAppBlocklist(path string) error {
 		newEntries = savedList.Blocked
 	}
 
+	if len(savedList.Rules) > 0 {
+		existingRules, err := LoadAppRules()
+		if err != nil {
+			return err
+		}
+		return SaveAppRules(append(existingRules, savedList.Rules...))
+	}
+
 	existingList, err := LoadAppBlocklist()
 	if err != nil {
 		return err
@@ -1,67 +1,78 @@
 package app
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"veda-anchor-engine/src/internal/blocklist/rulelist"
 	"veda-anchor-engine/src/internal/config"
 	"veda-anchor-engine/src/internal/platform/blocklistlock"
 )
 
-// LoadAppBlocklist reads the blocklist file from the user's cache directory.
-// It returns a slice of strings, with all entries normalized to lowercase for case-insensitive matching.
+// appRules is the app rule list in the app blocklist file.
+var appRules = rulelist.List[AppRule]{
+	Path:      config.GetAppBlocklistPath,
+	Name:      func(r AppRule) string { return r.Name },
+	Normalize: AppRule.normalize,
+	// Apply platform-specific file locking to prevent unauthorized modification.
+	Protect: blocklistlock.PlatformLock, // build-tag dispatch
+}
+
+// LoadAppRules reads the app rules from the blocklist file.
+// Entries written by older versions as plain process names are loaded as process rules.
 // If the file doesn't exist, it returns an empty list, which is not considered an error.
-func LoadAppBlocklist() ([]string, error) {
-	p, err := config.GetAppBlocklistPath()
-	if err != nil {
-		return nil, err
-	}
+func LoadAppRules() ([]AppRule, error) {
+	return appRules.Load()
+}
 
-	b, err := os.ReadFile(p)
-	if os.IsNotExist(err) {
-		return nil, nil // File not existing is not an error, just an empty list.
-	}
+// SaveAppRules writes the given rules to the blocklist file.
+// Names are normalized to lowercase and only the last rule for each name is kept.
+// It also sets appropriate file permissions to secure the file.
+func SaveAppRules(rules []AppRule) error {
+	return appRules.Save(rules)
+}
+
+// LoadAppBlocklist returns the names of the applications that are blocked themselves, normalized to
+// lowercase for case-insensitive matching. Names whose rule only blocks the processes they start and
+// group rules are not included; use LoadAppRules for those.
+func LoadAppBlocklist() ([]string, error) {
+	rules, err := LoadAppRules()
 	if err != nil {
 		return nil, err
 	}
 
 	var list []string
-	if err := json.Unmarshal(b, &list); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal blocklist: %w", err)
-	}
-
-	// Normalize all entries to lowercase to ensure case-insensitive comparison.
-	for i := range list {
-		list[i] = strings.ToLower(list[i])
+	for _, rule := range rules {
+		if rule.BlocksApp() && !rule.Group {
+			list = append(list, rule.Name)
+		}
 	}
 	return list, nil
 }
 
-// SaveAppBlocklist writes the given list of strings to the blocklist file.
-// It normalizes all entries to lowercase before saving to ensure consistency.
-// It also sets appropriate file permissions to secure the file.
+// SaveAppBlocklist replaces the blocked applications with the given list.
+// Names that stay blocked keep their scope, and a name that only had its descendants blocked
+// becomes blocked with its whole subtree. Group rules, and rules for names not in the list that
+// don't block the application itself, are preserved.
 func SaveAppBlocklist(list []string) error {
-	// Normalize all entries to lowercase to ensure consistency.
-	for i := range list {
-		list[i] = strings.ToLower(list[i])
-	}
-
-	p, err := config.GetAppBlocklistPath()
+	rules, err := LoadAppRules()
 	if err != nil {
 		return err
 	}
-	_ = os.MkdirAll(filepath.Dir(p), 0755)
 
-	b, err := json.MarshalIndent(list, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal blocklist: %w", err)
-	}
-	if err := os.WriteFile(p, b, 0600); err != nil {
-		return err
+	// Normalize all entries to lowercase to ensure consistency.
+	for i := range list {
+		list[i] = strings.ToLower(list[i])
 	}
 
-	// Apply platform-specific file locking to prevent unauthorized modification.
-	return blocklistlock.PlatformLock(p) // build-tag dispatch
+	kept := slices.DeleteFunc(slices.Clone(rules), func(r AppRule) bool {
+		return !r.Group && (r.BlocksApp() || slices.Contains(list, r.Name))
+	})
+	for _, name := range list {
+		scope := ScopeProcess
+		if idx := slices.IndexFunc(rules, func(r AppRule) bool { return !r.Group && r.Name == name }); idx != -1 && rules[idx].BlocksDescendants() {
+			scope = ScopeSubtree
+		}
+		kept = append(kept, AppRule{Name: name, Scope: scope})
+	}
+	return SaveAppRules(kept)
 }
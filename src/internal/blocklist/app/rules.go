@@ -0,0 +1,74 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"veda-anchor-engine/src/internal/blocklist/rulelist"
+)
+
+// Scope determines which processes an app rule terminates.
+type Scope string
+
+const (
+	// ScopeProcess terminates the named application only.
+	ScopeProcess Scope = "process"
+	// ScopeSubtree terminates the named application and every process it started, directly or not.
+	ScopeSubtree Scope = "subtree"
+	// ScopeDescendants terminates every process started by the named application, directly or not,
+	// but leaves the application itself running, e.g. to block anything launched by a game launcher.
+	ScopeDescendants Scope = "descendants"
+)
+
+// AppRule associates a process name, or an application group, with the processes to terminate.
+type AppRule struct {
+	Name  string `json:"name"`
+	Scope Scope  `json:"scope"`
+	// Group makes Name the ID of an application group (see package appgroup) instead of a process
+	// name: the rule covers every executable of the group.
+	Group bool `json:"group,omitempty"`
+}
+
+// UnmarshalJSON accepts both the rule object and the legacy plain process name, read as a process rule.
+func (r *AppRule) UnmarshalJSON(b []byte) error {
+	type rawRule AppRule
+	var raw rawRule
+	if err := rulelist.Unmarshal(b, &raw.Name, &raw); err != nil {
+		return err
+	}
+	*r = AppRule(raw)
+	if r.Scope == "" {
+		r.Scope = ScopeProcess
+	}
+	return nil
+}
+
+// ParseScope validates a scope string. An empty string is treated as ScopeProcess.
+func ParseScope(s string) (Scope, error) {
+	switch scope := Scope(strings.ToLower(strings.TrimSpace(s))); scope {
+	case "":
+		return ScopeProcess, nil
+	case ScopeProcess, ScopeSubtree, ScopeDescendants:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown app rule scope: %s", s)
+	}
+}
+
+// BlocksApp reports whether the rule terminates the named application itself.
+func (r AppRule) BlocksApp() bool {
+	return r.Scope != ScopeDescendants
+}
+
+// BlocksDescendants reports whether the rule terminates the processes started by the named application.
+func (r AppRule) BlocksDescendants() bool {
+	return r.Scope == ScopeSubtree || r.Scope == ScopeDescendants
+}
+
+// normalize lowercases the name and fills in the default scope.
+func (r AppRule) normalize() AppRule {
+	r.Name = strings.ToLower(strings.TrimSpace(r.Name))
+	if r.Scope == "" {
+		r.Scope = ScopeProcess
+	}
+	return r
+}
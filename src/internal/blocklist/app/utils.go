@@ -51,3 +51,20 @@ func RemoveAppFromBlocklist(name string) (string, error) {
 func ClearAppBlocklist() error {
 	return SaveAppBlocklist([]string{})
 }
+
+// SetAppRule adds a rule for a process name or application group, or replaces the existing one.
+func SetAppRule(rule AppRule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	if _, err := ParseScope(string(rule.Scope)); err != nil {
+		return err
+	}
+
+	return appRules.Set(rule)
+}
+
+// RemoveAppRule removes the rule for a process name or application group ID, whatever its scope.
+func RemoveAppRule(name string) (string, error) {
+	return appRules.Remove(name)
+}
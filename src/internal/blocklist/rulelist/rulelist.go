@@ -0,0 +1,132 @@
+package rulelist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// This package holds what the app and web blocklists have in common: a JSON file holding a list of
+// rules, each identified by a name (a process name, application group ID or domain).
+//
+// DESIGN DECISION:
+// Older versions stored each blocklist as a plain list of names. Such entries are still read, as the
+// default rule for the name, so existing blocklist files keep working; they are rewritten as rule
+// objects the next time the list is saved.
+
+// List is a rule list stored in a blocklist file.
+type List[R any] struct {
+	// Path returns the path of the blocklist file.
+	Path func() (string, error)
+	// Name returns the name that identifies a rule.
+	Name func(R) string
+	// Normalize lowercases the name of a rule and fills in its defaults.
+	Normalize func(R) R
+	// Protect, if set, is applied to the file after it is written.
+	Protect func(path string) error
+}
+
+// Unmarshal decodes a rule into v, or, for an entry an older version stored as a plain name, sets
+// name to it and leaves the rest of v alone. v must not be the rule type itself, whose UnmarshalJSON
+// would call Unmarshal again, but a type defined from it.
+func Unmarshal(b []byte, name *string, v any) error {
+	if err := json.Unmarshal(b, name); err == nil {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// Load reads the rules from the blocklist file.
+// If the file doesn't exist, it returns an empty list, which is not considered an error.
+func (l List[R]) Load() ([]R, error) {
+	p, err := l.Path()
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return []R{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []R
+	if err := json.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %w", filepath.Base(p), err)
+	}
+
+	// Normalize all entries to lowercase for case-insensitive comparison.
+	for i := range rules {
+		rules[i] = l.Normalize(rules[i])
+	}
+	return rules, nil
+}
+
+// Save writes the given rules to the blocklist file.
+// Names are normalized to lowercase and only the last rule for each name is kept.
+func (l List[R]) Save(rules []R) error {
+	normalized := make([]R, 0, len(rules))
+	for _, rule := range rules {
+		rule = l.Normalize(rule)
+		name := l.Name(rule)
+		if name == "" {
+			continue
+		}
+		normalized = slices.DeleteFunc(normalized, func(r R) bool {
+			return l.Name(r) == name
+		})
+		normalized = append(normalized, rule)
+	}
+
+	p, err := l.Path()
+	if err != nil {
+		return err
+	}
+	_ = os.MkdirAll(filepath.Dir(p), 0755)
+
+	b, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", filepath.Base(p), err)
+	}
+	if err := os.WriteFile(p, b, 0600); err != nil {
+		return err
+	}
+	if l.Protect != nil {
+		return l.Protect(p)
+	}
+	return nil
+}
+
+// Set adds a rule, or replaces the existing rule for its name.
+func (l List[R]) Set(rule R) error {
+	rules, err := l.Load()
+	if err != nil {
+		return err
+	}
+	return l.Save(append(rules, rule))
+}
+
+// Remove removes the rule for a name. It returns "removed", or "not found" if there is no such rule.
+func (l List[R]) Remove(name string) (string, error) {
+	rules, err := l.Load()
+	if err != nil {
+		return "", err
+	}
+
+	lowerName := strings.ToLower(name)
+	idx := slices.IndexFunc(rules, func(r R) bool { return l.Name(r) == lowerName })
+	if idx == -1 {
+		return "not found", nil
+	}
+
+	rules = slices.Delete(rules, idx, idx+1)
+	if err := l.Save(rules); err != nil {
+		return "", fmt.Errorf("save: %w", err)
+	}
+	return "removed", nil
+}
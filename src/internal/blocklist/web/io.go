@@ -1,62 +1,67 @@
 package web
 
 import (
-	"encoding/json"
-	"fmt"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
+	"veda-anchor-engine/src/internal/blocklist/rulelist"
 	"veda-anchor-engine/src/internal/config"
 )
 
-// LoadWebBlocklist reads the web blocklist file from the user's cache directory.
-// It returns a slice of strings, with all entries normalized to lowercase for case-insensitive matching.
+// webRules is the web rule list in the web blocklist file.
+var webRules = rulelist.List[WebRule]{
+	Path:      config.GetWebBlocklistPath,
+	Name:      func(r WebRule) string { return r.Domain },
+	Normalize: WebRule.normalize,
+}
+
+// LoadWebRules reads the web rules from the blocklist file.
+// Entries written by older versions as plain domain strings are loaded as block rules.
 // If the file doesn't exist, it returns an empty list, which is not considered an error.
-func LoadWebBlocklist() ([]string, error) {
-	p, err := config.GetWebBlocklistPath()
-	if err != nil {
-		return nil, err
-	}
+func LoadWebRules() ([]WebRule, error) {
+	return webRules.Load()
+}
 
-	// If the blocklist file doesn't exist, return an empty list.
-	b, err := os.ReadFile(p)
-	if os.IsNotExist(err) {
-		return []string{}, nil
-	}
+// SaveWebRules writes the given rules to the blocklist file.
+// Domains are normalized to lowercase and only the last rule for each domain is kept.
+func SaveWebRules(rules []WebRule) error {
+	return webRules.Save(rules)
+}
+
+// LoadWebBlocklist returns the domains that are hard-blocked.
+// Domains with friction rules (delay, warn, log-only) are not included; use LoadWebRules for those.
+func LoadWebBlocklist() ([]string, error) {
+	rules, err := LoadWebRules()
 	if err != nil {
 		return nil, err
 	}
 
-	var list []string
-	if err := json.Unmarshal(b, &list); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal web blocklist: %w", err)
-	}
-
-	// Normalize all entries to lowercase for case-insensitive comparison.
-	for i := range list {
-		list[i] = strings.ToLower(list[i])
+	list := []string{}
+	for _, rule := range rules {
+		if rule.Action == ActionBlock {
+			list = append(list, rule.Domain)
+		}
 	}
 	return list, nil
 }
 
-// SaveWebBlocklist writes the given list of strings to the web blocklist file.
-// It normalizes all entries to lowercase before saving to ensure consistency.
+// SaveWebBlocklist replaces the hard-blocked domains with the given list.
+// Friction rules for other domains are preserved; a domain in the list always becomes a block rule.
 func SaveWebBlocklist(list []string) error {
+	rules, err := LoadWebRules()
+	if err != nil {
+		return err
+	}
+
 	// Normalize all entries to lowercase to ensure consistency.
 	for i := range list {
 		list[i] = strings.ToLower(list[i])
 	}
 
-	p, err := config.GetWebBlocklistPath()
-	if err != nil {
-		return err
-	}
-	_ = os.MkdirAll(filepath.Dir(p), 0755)
-
-	// Marshal the list to JSON with indentation for readability.
-	b, err := json.MarshalIndent(list, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal web blocklist: %w", err)
+	rules = slices.DeleteFunc(rules, func(r WebRule) bool {
+		return r.Action == ActionBlock || slices.Contains(list, r.Domain)
+	})
+	for _, domain := range list {
+		rules = append(rules, WebRule{Domain: domain, Action: ActionBlock})
 	}
-	return os.WriteFile(p, b, 0600)
+	return SaveWebRules(rules)
 }
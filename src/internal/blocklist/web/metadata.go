@@ -2,7 +2,9 @@ package web
 
 // BlockedWebsiteDetail represents the details of a blocked website.
 type BlockedWebsiteDetail struct {
-	Domain  string `json:"domain"`
-	Title   string `json:"title"`
-	IconURL string `json:"iconUrl"`
+	Domain       string `json:"domain"`
+	Title        string `json:"title"`
+	IconURL      string `json:"iconUrl"`
+	Action       Action `json:"action"`
+	DelaySeconds int    `json:"delaySeconds,omitempty"`
 }
@@ -0,0 +1,95 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+	"veda-anchor-engine/src/internal/blocklist/rulelist"
+)
+
+// Action determines how the extension treats a visit to a domain covered by a rule.
+type Action string
+
+const (
+	// ActionBlock replaces the page with the block page.
+	ActionBlock Action = "block"
+	// ActionDelay shows an interstitial with a countdown before the page can be opened.
+	ActionDelay Action = "delay"
+	// ActionWarn shows a click-through warning page.
+	ActionWarn Action = "warn"
+	// ActionLogOnly records the visit without intervening.
+	ActionLogOnly Action = "log-only"
+	// ActionAllow is reported for visits that no rule applies to. It is never stored in a rule.
+	ActionAllow Action = "allow"
+)
+
+// DefaultDelaySeconds is the countdown used by delay rules that don't specify one.
+const DefaultDelaySeconds = 10
+
+// WebRule associates a domain with the action the extension should take.
+// A rule for a domain also covers all of its subdomains.
+type WebRule struct {
+	Domain       string `json:"domain"`
+	Action       Action `json:"action"`
+	DelaySeconds int    `json:"delaySeconds,omitempty"`
+}
+
+// UnmarshalJSON accepts both the rule object and the legacy plain domain string, read as a block rule.
+func (r *WebRule) UnmarshalJSON(b []byte) error {
+	type rawRule WebRule
+	var raw rawRule
+	if err := rulelist.Unmarshal(b, &raw.Domain, &raw); err != nil {
+		return err
+	}
+	*r = WebRule(raw)
+	if r.Action == "" {
+		r.Action = ActionBlock
+	}
+	return nil
+}
+
+// ParseAction validates an action string. An empty string is treated as ActionBlock.
+func ParseAction(s string) (Action, error) {
+	switch a := Action(strings.ToLower(strings.TrimSpace(s))); a {
+	case "":
+		return ActionBlock, nil
+	case ActionBlock, ActionDelay, ActionWarn, ActionLogOnly:
+		return a, nil
+	default:
+		return "", fmt.Errorf("unknown web rule action: %s", s)
+	}
+}
+
+// normalize lowercases the domain and fills in defaults for the action.
+func (r WebRule) normalize() WebRule {
+	r.Domain = strings.ToLower(strings.TrimSpace(r.Domain))
+	if r.Action == "" {
+		r.Action = ActionBlock
+	}
+	if r.Action == ActionDelay && r.DelaySeconds <= 0 {
+		r.DelaySeconds = DefaultDelaySeconds
+	}
+	if r.Action != ActionDelay {
+		r.DelaySeconds = 0
+	}
+	return r
+}
+
+// MatchRule returns the rule that applies to the given host.
+// The most specific domain wins, so a rule for "mail.example.com" overrides one for "example.com".
+func MatchRule(rules []WebRule, host string) (WebRule, bool) {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+
+	var best WebRule
+	bestLen := -1
+	for _, rule := range rules {
+		domain := strings.TrimPrefix(rule.Domain, "www.")
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if len(domain) > bestLen {
+			best = rule
+			bestLen = len(domain)
+		}
+	}
+	return best, bestLen >= 0
+}
@@ -51,3 +51,20 @@ func RemoveWebsiteFromBlocklist(domain string) (string, error) {
 func ClearWebBlocklist() error {
 	return SaveWebBlocklist([]string{})
 }
+
+// SetWebRule adds a rule for a domain or replaces the existing one.
+func SetWebRule(rule WebRule) error {
+	if strings.TrimSpace(rule.Domain) == "" {
+		return fmt.Errorf("domain is required")
+	}
+	if _, err := ParseAction(string(rule.Action)); err != nil {
+		return err
+	}
+
+	return webRules.Set(rule)
+}
+
+// RemoveWebRule removes the rule for a domain, whatever its action.
+func RemoveWebRule(domain string) (string, error) {
+	return webRules.Remove(domain)
+}
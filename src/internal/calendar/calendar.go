@@ -0,0 +1,321 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// Embed the timezone database, which Windows doesn't provide in the format Go needs.
+	_ "time/tzdata"
+	"veda-anchor-engine/src/internal/config"
+)
+
+// This package computes the boundaries of calendar periods (days, weeks, months) for statistics
+// and relative time ranges.
+//
+// DESIGN DECISION:
+// Every "today" or "this week" in the engine goes through a Calendar, so the user's calendar settings
+// apply consistently: the daily rollups, screen time and leaderboards all share the day window of
+// StartOfDay, in the configured timezone and from the configured hour. The settings are read once and
+// cached, since boundaries are computed on hot paths; Reload must be called whenever they change.
+
+// Calendar delimits calendar periods according to the calendar settings.
+type Calendar struct {
+	weekStart time.Weekday
+	location  *time.Location
+	// dayStart is the hour at which days start.
+	dayStart int
+}
+
+var (
+	mu      sync.Mutex
+	current *Calendar
+)
+
+// Current returns the calendar of the configured settings.
+// If the settings can't be read, it falls back to the defaults.
+func Current() Calendar {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		settings := config.CalendarSettings{}
+		if cfg, err := config.LoadConfig(); err == nil {
+			settings = cfg.Calendar
+		}
+		c, err := New(settings)
+		if err != nil {
+			c, _ = New(config.CalendarSettings{})
+		}
+		current = &c
+	}
+	return *current
+}
+
+// Reload makes the next Current read the settings again.
+func Reload() {
+	mu.Lock()
+	defer mu.Unlock()
+	current = nil
+}
+
+// New returns the calendar of the given settings.
+func New(settings config.CalendarSettings) (Calendar, error) {
+	if err := Validate(settings); err != nil {
+		return Calendar{}, err
+	}
+	weekStart, ok := parseWeekday(settings.WeekStart)
+	if !ok {
+		weekStart = localeWeekStart(settings.Locale)
+	}
+	location := time.Local
+	if settings.Timezone != "" {
+		location, _ = time.LoadLocation(settings.Timezone)
+	}
+	return Calendar{weekStart: weekStart, location: location, dayStart: settings.DayStartHour}, nil
+}
+
+// Validate checks calendar settings.
+func Validate(settings config.CalendarSettings) error {
+	if settings.WeekStart != "" {
+		if _, ok := parseWeekday(settings.WeekStart); !ok {
+			return fmt.Errorf("invalid week start %q", settings.WeekStart)
+		}
+	}
+	if settings.Locale != "" && localeRegion(settings.Locale) == "" && !isLanguageOnly(settings.Locale) {
+		return fmt.Errorf("invalid locale %q", settings.Locale)
+	}
+	if settings.DayStartHour < 0 || settings.DayStartHour > 23 {
+		return fmt.Errorf("day start hour must be between 0 and 23")
+	}
+	if settings.Timezone != "" {
+		// LoadLocation also accepts "Local", which isn't an IANA name.
+		if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "Local" {
+			return fmt.Errorf("invalid timezone %q", settings.Timezone)
+		}
+	}
+	return nil
+}
+
+// WeekStart returns the first day of the week.
+func (c Calendar) WeekStart() time.Weekday {
+	return c.weekStart
+}
+
+// Location returns the timezone days are delimited in.
+func (c Calendar) Location() *time.Location {
+	return c.location
+}
+
+// Day returns the start of the day of the given date.
+func (c Calendar) Day(year int, month time.Month, day int) time.Time {
+	// time.Date moves a start hour skipped by daylight saving time to the next valid time.
+	return time.Date(year, month, day, c.dayStart, 0, 0, 0, c.location)
+}
+
+// DayWindow returns the day t belongs to as [start, end). Days start at the configured hour,
+// so with a day start of 4, 2 AM belongs to the previous day. A day lasts 23 or 25 hours when
+// daylight saving time starts or ends.
+func (c Calendar) DayWindow(t time.Time) (start, end time.Time) {
+	t = t.In(c.location)
+	year, month, day := t.Date()
+	if t.Hour() < c.dayStart {
+		day--
+	}
+	return c.Day(year, month, day), c.Day(year, month, day+1)
+}
+
+// StartOfDay returns the start of the day t belongs to.
+func (c Calendar) StartOfDay(t time.Time) time.Time {
+	start, _ := c.DayWindow(t)
+	return start
+}
+
+// Date returns the date of the day t belongs to, such as "2006-01-02".
+func (c Calendar) Date(t time.Time) string {
+	return c.StartOfDay(t).Format(DateLayout)
+}
+
+// ParseDate returns the start of the day of a date formatted like Date.
+func (c Calendar) ParseDate(date string) (time.Time, error) {
+	d, err := time.Parse(DateLayout, date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return c.Day(d.Date()), nil
+}
+
+// Weekday returns the day of the week of the day t belongs to.
+func (c Calendar) Weekday(t time.Time) time.Weekday {
+	return c.StartOfDay(t).Weekday()
+}
+
+// StartOfWeek returns the start of the week t belongs to.
+func (c Calendar) StartOfWeek(t time.Time) time.Time {
+	year, month, day := c.StartOfDay(t).Date()
+	offset := (int(c.Weekday(t)) - int(c.weekStart) + 7) % 7
+	return c.Day(year, month, day-offset)
+}
+
+// StartOfMonth returns the start of the month t belongs to.
+func (c Calendar) StartOfMonth(t time.Time) time.Time {
+	year, month, _ := c.StartOfDay(t).Date()
+	return c.Day(year, month, 1)
+}
+
+// StartOfYear returns the start of the year t belongs to.
+func (c Calendar) StartOfYear(t time.Time) time.Time {
+	year, _, _ := c.StartOfDay(t).Date()
+	return c.Day(year, time.January, 1)
+}
+
+// AddDays returns the start of the day n days after the day starting at start.
+func (c Calendar) AddDays(start time.Time, n int) time.Time {
+	year, month, day := start.In(c.location).Date()
+	return c.Day(year, month, day+n)
+}
+
+// AddMonths returns the start of the day n months after the day starting at start.
+func (c Calendar) AddMonths(start time.Time, n int) time.Time {
+	year, month, day := start.In(c.location).Date()
+	return c.Day(year, month+time.Month(n), day)
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// sundayRegions and saturdayRegions list the regions whose weeks don't start on Monday,
+// following the CLDR week data. All others start on Monday, as in ISO 8601.
+var (
+	sundayRegions = map[string]bool{
+		"AG": true, "AS": true, "BD": true, "BR": true, "BS": true, "BT": true, "BW": true, "BZ": true,
+		"CA": true, "CN": true, "CO": true, "DM": true, "DO": true, "ET": true, "GT": true, "GU": true,
+		"HK": true, "HN": true, "ID": true, "IL": true, "IN": true, "JM": true, "JP": true, "KE": true,
+		"KH": true, "KR": true, "LA": true, "MH": true, "MM": true, "MO": true, "MT": true, "MX": true,
+		"MZ": true, "NI": true, "NP": true, "PA": true, "PE": true, "PH": true, "PK": true, "PR": true,
+		"PT": true, "PY": true, "SA": true, "SG": true, "SV": true, "TH": true, "TT": true, "TW": true,
+		"UM": true, "US": true, "VE": true, "VI": true, "WS": true, "YE": true, "ZA": true, "ZW": true,
+	}
+	saturdayRegions = map[string]bool{
+		"AE": true, "AF": true, "BH": true, "DJ": true, "DZ": true, "EG": true, "IQ": true, "IR": true,
+		"JO": true, "KW": true, "LY": true, "OM": true, "QA": true, "SD": true, "SY": true,
+	}
+)
+
+// localeWeekStart returns the first day of the week in a locale.
+func localeWeekStart(locale string) time.Weekday {
+	region := localeRegion(locale)
+	switch {
+	case sundayRegions[region]:
+		return time.Sunday
+	case saturdayRegions[region]:
+		return time.Saturday
+	default:
+		return time.Monday
+	}
+}
+
+// localeRegion extracts the two-letter region of a language tag such as "en-US" or "pt_BR".
+func localeRegion(locale string) string {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	for _, p := range parts[min(1, len(parts)):] {
+		if len(p) == 2 && isLetters(p) {
+			return strings.ToUpper(p)
+		}
+	}
+	return ""
+}
+
+// isLanguageOnly reports whether a locale is a bare language subtag such as "en".
+func isLanguageOnly(locale string) bool {
+	return (len(locale) == 2 || len(locale) == 3) && isLetters(locale)
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// DateLayout is the layout of the dates of days, as used by the daily rollups.
+const DateLayout = "2006-01-02"
+
+// Bucket units of time series.
+const (
+	UnitHour  = "hour"
+	UnitDay   = "day"
+	UnitWeek  = "week"
+	UnitMonth = "month"
+)
+
+// StartOfHour returns the start of the hour t belongs to. It subtracts the minutes and seconds
+// instead of using time.Date, which is ambiguous for the repeated hour when daylight saving time ends.
+func (c Calendar) StartOfHour(t time.Time) time.Time {
+	t = t.In(c.location)
+	return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+}
+
+// Truncate returns the start of the bucket of the given unit that t belongs to.
+func (c Calendar) Truncate(unit string, t time.Time) (time.Time, error) {
+	switch unit {
+	case UnitHour:
+		return c.StartOfHour(t), nil
+	case UnitDay:
+		return c.StartOfDay(t), nil
+	case UnitWeek:
+		return c.StartOfWeek(t), nil
+	case UnitMonth:
+		return c.StartOfMonth(t), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown bucket unit %q", unit)
+}
+
+// Next returns the start of the bucket following the one starting at start.
+// Days, weeks and months follow the calendar, so a day lasts 23 or 25 hours when daylight saving time
+// starts or ends; hours always last 60 minutes.
+func (c Calendar) Next(unit string, start time.Time) time.Time {
+	switch unit {
+	case UnitHour:
+		return start.Add(time.Hour)
+	case UnitDay:
+		return c.AddDays(start, 1)
+	case UnitWeek:
+		return c.AddDays(start, 7)
+	default:
+		return c.AddMonths(start, 1)
+	}
+}
+
+// Buckets returns the starts of the buckets covering [since, until), followed by the end of the last one.
+// It fails if more than maxBuckets would be needed.
+func (c Calendar) Buckets(unit string, since, until time.Time, maxBuckets int) ([]time.Time, error) {
+	start, err := c.Truncate(unit, since)
+	if err != nil {
+		return nil, err
+	}
+	bounds := []time.Time{start}
+	for cur := start; cur.Before(until); {
+		cur = c.Next(unit, cur)
+		bounds = append(bounds, cur)
+		if len(bounds) > maxBuckets+1 {
+			return nil, fmt.Errorf("range is too long for %s buckets (at most %d)", unit, maxBuckets)
+		}
+	}
+	if len(bounds) == 1 {
+		bounds = append(bounds, c.Next(unit, start))
+	}
+	return bounds, nil
+}
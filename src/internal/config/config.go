@@ -13,11 +13,146 @@ type Config struct {
 	AutostartEnabled bool `json:"autostart_enabled,omitempty"`
 	// PasswordHash stores the bcrypt hash of the GUI password.
 	PasswordHash string `json:"password_hash,omitempty"`
+	// Retention controls how long raw history is kept in the database.
+	Retention RetentionSettings `json:"retention"`
+	// Backup controls the scheduled automatic backups.
+	Backup BackupSettings `json:"backup"`
+	// Calendar controls how days and weeks are delimited in statistics and time ranges.
+	Calendar CalendarSettings `json:"calendar"`
+	// Idle controls when the user counts as away from the computer.
+	Idle IdleSettings `json:"idle"`
+	// Titles controls whether and how window titles are recorded with screen time.
+	Titles TitleSettings `json:"titles"`
+	// AppGroups controls how executables are grouped into applications.
+	AppGroups AppGroupSettings `json:"app_groups"`
+}
+
+// AppGroupSettings defines how executables are grouped into logical applications for statistics and blocking.
+// Executables signed by the same publisher with the same product name form a group; other executables
+// are grouped by their normalized path.
+type AppGroupSettings struct {
+	// PathRules rewrite lowercased executable paths before they are compared, in order, e.g. to remove
+	// the version directory of applications that move to a new one on every update.
+	PathRules []PathRule `json:"path_rules"`
+	// Merges combine groups into a single application.
+	Merges []AppMerge `json:"merges"`
+}
+
+// PathRule replaces the matches of a regular expression in executable paths. Replacement may refer to groups as $1.
+type PathRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// AppMerge is a user-defined application made of several groups.
+type AppMerge struct {
+	Name string `json:"name"`
+	// Members are group IDs, executable paths or file names such as "steamwebhelper.exe".
+	Members []string `json:"members"`
+}
+
+// DefaultAppGroups returns the grouping settings used when the settings file doesn't specify any.
+func DefaultAppGroups() AppGroupSettings {
+	return AppGroupSettings{
+		PathRules: []PathRule{
+			// Squirrel installs, e.g. ...\discord\app-1.0.9012\discord.exe.
+			{Pattern: `\\app-\d+(\.\d+)*\\`, Replacement: `\app-*\`},
+			// Version directories, e.g. ...\chrome\application\120.0.6099.71\.
+			{Pattern: `\\\d+(\.\d+){2,}\\`, Replacement: `\*\`},
+		},
+	}
+}
+
+// TitleSettings defines how the titles of foreground windows are recorded.
+type TitleSettings struct {
+	// Enabled turns on window title capture. When it is off, titles are neither requested nor stored.
+	Enabled bool `json:"enabled"`
+	// Rules rewrite titles before they are stored, in order.
+	Rules []TitleRule `json:"rules"`
+}
+
+// TitleRule replaces the matches of a regular expression in window titles, e.g. Pattern " - .*$"
+// with an empty Replacement strips everything after " - ". Replacement may refer to groups as $1.
+// A title that becomes empty isn't recorded.
+type TitleRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// IdleSettings defines when the user counts as idle. Idle time isn't counted as screen time.
+type IdleSettings struct {
+	// ThresholdSeconds is the time without keyboard or mouse input after which the user is idle.
+	// 0 disables idle detection.
+	ThresholdSeconds int `json:"threshold_seconds"`
+}
+
+// DefaultIdle returns the idle settings used when the settings file doesn't specify any.
+func DefaultIdle() IdleSettings {
+	return IdleSettings{
+		ThresholdSeconds: 300,
+	}
+}
+
+// CalendarSettings defines the calendar used for daily statistics and relative time ranges such as "this week".
+type CalendarSettings struct {
+	// WeekStart is the first day of the week, e.g. "monday" or "sunday". If empty, it follows Locale.
+	WeekStart string `json:"week_start"`
+	// Locale is a language tag such as "en-US". Its region decides the first day of the week.
+	Locale string `json:"locale"`
+	// DayStartHour is the hour at which a day starts, 0-23. With 4, usage until 4 AM counts towards the
+	// previous day, and daily statistics reset at 4 AM.
+	DayStartHour int `json:"day_start_hour"`
+	// Timezone is the IANA name of the timezone days are delimited in, e.g. "Europe/Berlin".
+	// If empty, the system timezone is used.
+	Timezone string `json:"timezone"`
+}
+
+// BackupSettings defines how often automatic backups are taken and how many are kept.
+type BackupSettings struct {
+	// IntervalHours is the time between two automatic backups. 0 disables them.
+	IntervalHours int `json:"interval_hours"`
+	// Keep is the number of automatic backups kept; older ones are deleted.
+	Keep int `json:"keep"`
+}
+
+// DefaultBackup returns the backup settings used when the settings file doesn't specify any.
+func DefaultBackup() BackupSettings {
+	return BackupSettings{
+		Keep: 7,
+	}
+}
+
+// RetentionSettings defines how long each kind of raw data is kept before it is pruned.
+// A value of 0 keeps the data forever.
+type RetentionSettings struct {
+	// AppEventsDays is the retention for process start/stop events.
+	AppEventsDays int `json:"app_events_days"`
+	// WebEventsDays is the retention for raw website visits.
+	WebEventsDays int `json:"web_events_days"`
+	// ScreenTimeDays is the retention for foreground usage and idle records.
+	ScreenTimeDays int `json:"screen_time_days"`
+	// LogsDays is the retention for the logs table.
+	LogsDays int `json:"logs_days"`
+	// MaxDatabaseMB caps the space used by the database. Oldest raw data is pruned beyond it.
+	MaxDatabaseMB int `json:"max_database_mb"`
+}
+
+// DefaultRetention returns the retention used when the settings file doesn't specify one.
+func DefaultRetention() RetentionSettings {
+	return RetentionSettings{
+		WebEventsDays: 90,
+		LogsDays:      14,
+	}
 }
 
 // NewConfig creates a new Config with default values.
 func NewConfig() *Config {
-	return &Config{}
+	return &Config{
+		Retention: DefaultRetention(),
+		Backup:    DefaultBackup(),
+		Idle:      DefaultIdle(),
+		AppGroups: DefaultAppGroups(),
+	}
 }
 
 // GetConfigPath returns the path to the configuration file.
@@ -47,14 +182,15 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
-	if err := json.Unmarshal(content, &config); err != nil {
+	// Unmarshal over the defaults so settings added in newer versions get sensible values.
+	config := NewConfig()
+	if err := json.Unmarshal(content, config); err != nil {
 		// If the file is corrupted or invalid, it's better to return an error
 		// than to proceed with a potentially broken configuration.
 		return nil, err
 	}
 
-	return &config, nil
+	return config, nil
 }
 
 // Save writes the current configuration to the configuration file.
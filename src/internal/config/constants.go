@@ -10,3 +10,7 @@ const (
 	// PipeName is the named pipe address for IPC
 	PipeName = `\\.\pipe\veda-anchor`
 )
+
+// Version is the engine version, set at build time with
+// -ldflags "-X veda-anchor-engine/src/internal/config.Version=...".
+var Version = "dev"
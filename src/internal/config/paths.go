@@ -69,6 +69,24 @@ func GetDatabasePath() (string, error) {
 	return filepath.Join(root, "veda-anchor.db"), nil
 }
 
+// GetBackupDir returns the directory where database backups are stored.
+func GetBackupDir() (string, error) {
+	root, err := GetAppRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "backups"), nil
+}
+
+// GetSpoolDir returns the directory where writes are journaled while the database is unavailable.
+func GetSpoolDir() (string, error) {
+	root, err := GetAppRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, "spool"), nil
+}
+
 // GetHeartbeatPath returns the path to the extension heartbeat file.
 func GetHeartbeatPath() (string, error) {
 	root, err := GetAppRoot()
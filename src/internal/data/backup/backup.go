@@ -0,0 +1,201 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/schema"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// This package creates and restores backups of the full engine state.
+//
+// DESIGN DECISION:
+// A backup is a zip archive holding a consistent database snapshot, the settings, both blocklists
+// and a manifest with the SHA-256 of every file. The snapshot is taken with VACUUM INTO while the
+// writer is paused, so it contains exactly the writes that were queued before the backup started.
+// Restores go through SQLite's backup API into the open database rather than replacing the file,
+// which other processes (e.g. the native messaging host) may hold open.
+
+const (
+	// FormatVersion is the version of the archive layout.
+	FormatVersion = 1
+
+	manifestName = "manifest.json"
+	databaseName = "veda-anchor.db"
+
+	// pauseTimeout bounds how long a backup or restore waits for the writer to drain.
+	pauseTimeout = 30 * time.Second
+)
+
+// Manifest describes the contents of a backup archive.
+type Manifest struct {
+	FormatVersion int            `json:"format_version"`
+	EngineVersion string         `json:"engine_version"`
+	SchemaVersion int            `json:"schema_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Files         []ManifestFile `json:"files"`
+}
+
+// ManifestFile is a file stored in a backup archive.
+type ManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// stateFile is a file outside the database that is part of the engine state.
+type stateFile struct {
+	name string
+	path func() (string, error)
+}
+
+// stateFiles lists the files, besides the database, that are backed up.
+var stateFiles = []stateFile{
+	{name: "settings.json", path: config.GetConfigPath},
+	{name: "web_blocklist.json", path: config.GetWebBlocklistPath},
+	{name: "app_blocklist.json", path: config.GetAppBlocklistPath},
+}
+
+// DefaultPath returns the path of a new backup archive in the backup directory.
+func DefaultPath(prefix string) (string, error) {
+	dir, err := config.GetBackupDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.zip", prefix, time.Now().Format("20060102-150405"))), nil
+}
+
+// Create writes a backup archive of the database and the state files to dest.
+// The archive is written to a temporary file first, so dest never holds a partial backup.
+func Create(db *sql.DB, dest string) (*Manifest, error) {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return nil, err
+	}
+	workDir, err := os.MkdirTemp(filepath.Dir(dest), ".backup-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	snapshot := filepath.Join(workDir, databaseName)
+	version, err := snapshotDatabase(db, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("could not snapshot database: %w", err)
+	}
+
+	manifest := &Manifest{
+		FormatVersion: FormatVersion,
+		EngineVersion: config.Version,
+		SchemaVersion: version,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	tmp := dest + ".tmp"
+	if err := writeArchive(tmp, snapshot, manifest); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		_ = os.Remove(tmp)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// snapshotDatabase writes a consistent copy of the database to path and returns its schema version.
+func snapshotDatabase(db *sql.DB, path string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), pauseTimeout)
+	defer cancel()
+	resume, err := write.Pause(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer resume()
+
+	version, err := schema.CurrentVersion(db)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// writeArchive writes the snapshot, the state files that exist and the manifest to a zip file.
+func writeArchive(path, snapshot string, manifest *Manifest) (err error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	zw := zip.NewWriter(f)
+	if err := addFile(zw, manifest, databaseName, snapshot); err != nil {
+		return err
+	}
+	for _, sf := range stateFiles {
+		src, err := sf.path()
+		if err != nil {
+			return err
+		}
+		if err := addFile(zw, manifest, sf.name, src); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// E.g. no blocklist has been saved yet; restoring removes the file as well.
+				continue
+			}
+			return err
+		}
+	}
+
+	// The manifest goes last, once all checksums are known.
+	w, err := zw.Create(manifestName)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// addFile copies a file into the archive and records it in the manifest.
+func addFile(zw *zip.Writer, manifest *Manifest, name, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, h), in)
+	if err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, ManifestFile{Name: name, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return nil
+}
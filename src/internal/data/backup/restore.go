@@ -0,0 +1,243 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/schema"
+	"veda-anchor-engine/src/internal/data/write"
+
+	"modernc.org/sqlite"
+)
+
+// restorer is implemented by the modernc.org/sqlite driver connection.
+type restorer interface {
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// Restore replaces the database content and the state files with the content of a backup archive.
+//
+// The archive is validated and its database migrated to the current schema in a temporary directory
+// before anything is touched. A backup of the current state is taken first, so a restore can be undone.
+// The caller is responsible for checking the password.
+func Restore(db *sql.DB, archivePath string) (*Manifest, error) {
+	root, err := config.GetAppRoot()
+	if err != nil {
+		return nil, err
+	}
+	// Extract next to the live files, so the final renames stay on the same volume.
+	workDir, err := os.MkdirTemp(root, ".restore-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(workDir) }()
+
+	manifest, err := extract(archivePath, workDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	snapshot := filepath.Join(workDir, databaseName)
+	if err := prepareSnapshot(snapshot); err != nil {
+		return nil, err
+	}
+
+	safety, err := DefaultPath("pre-restore")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := Create(db, safety); err != nil {
+		return nil, fmt.Errorf("could not back up current state: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), pauseTimeout)
+	defer cancel()
+	resume, err := write.Pause(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer resume()
+
+	if err := restoreDatabase(ctx, db, snapshot); err != nil {
+		return nil, fmt.Errorf("could not restore database: %w", err)
+	}
+	if err := swapStateFiles(workDir, manifest); err != nil {
+		return nil, fmt.Errorf("could not restore settings: %w", err)
+	}
+	return manifest, nil
+}
+
+// extract validates the manifest and the checksums of an archive and extracts its files into dir.
+func extract(archivePath, dir string) (*Manifest, error) {
+	zr, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	mf, ok := entries[manifestName]
+	if !ok {
+		return nil, errors.New("manifest is missing")
+	}
+	var manifest Manifest
+	if err := readJSON(mf, &manifest); err != nil {
+		return nil, fmt.Errorf("could not read manifest: %w", err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format %d", manifest.FormatVersion)
+	}
+
+	allowed := map[string]bool{databaseName: true}
+	for _, sf := range stateFiles {
+		allowed[sf.name] = true
+	}
+	hasDatabase := false
+	for _, file := range manifest.Files {
+		if !allowed[file.Name] {
+			return nil, fmt.Errorf("unexpected file %q", file.Name)
+		}
+		entry, ok := entries[file.Name]
+		if !ok {
+			return nil, fmt.Errorf("file %q is missing", file.Name)
+		}
+		if err := extractFile(entry, filepath.Join(dir, file.Name), file); err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		hasDatabase = hasDatabase || file.Name == databaseName
+	}
+	if !hasDatabase {
+		return nil, errors.New("database is missing")
+	}
+	return &manifest, nil
+}
+
+func readJSON(f *zip.File, v interface{}) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+	return json.NewDecoder(r).Decode(v)
+}
+
+// extractFile writes an archive entry to dest and checks it against the manifest.
+func extractFile(entry *zip.File, dest string, want ManifestFile) error {
+	r, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = r.Close() }()
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, h), r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	if size != want.Size || hex.EncodeToString(h.Sum(nil)) != want.SHA256 {
+		return errors.New("checksum mismatch")
+	}
+	return nil
+}
+
+// prepareSnapshot checks the integrity of an extracted database and migrates it to the current schema.
+func prepareSnapshot(path string) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = db.Close() }()
+
+	var result string
+	if err := db.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("could not check backup database: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup database is corrupt: %s", result)
+	}
+	// No pre-migration backup: the archive itself is the backup.
+	if err := schema.Migrate(db, ""); err != nil {
+		return fmt.Errorf("could not migrate backup database: %w", err)
+	}
+	return nil
+}
+
+// restoreDatabase copies the snapshot into the open database in a single step, which SQLite
+// applies atomically: other connections see either the old or the restored content.
+func restoreDatabase(ctx context.Context, db *sql.DB, snapshot string) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		r, ok := driverConn.(restorer)
+		if !ok {
+			return errors.New("database driver does not support restoring")
+		}
+		b, err := r.NewRestore(snapshot)
+		if err != nil {
+			return err
+		}
+		for {
+			more, err := b.Step(-1)
+			if err != nil {
+				_ = b.Finish()
+				return err
+			}
+			if !more {
+				return b.Finish()
+			}
+		}
+	})
+}
+
+// swapStateFiles moves the extracted state files into place, and removes those the backup didn't contain.
+// Each file is replaced with a rename, so readers never see a partially written file.
+func swapStateFiles(workDir string, manifest *Manifest) error {
+	inBackup := make(map[string]bool)
+	for _, f := range manifest.Files {
+		inBackup[f.Name] = true
+	}
+
+	for _, sf := range stateFiles {
+		dest, err := sf.path()
+		if err != nil {
+			return err
+		}
+		if !inBackup[sf.name] {
+			if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(workDir, sf.name), dest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
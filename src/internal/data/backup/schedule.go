@@ -0,0 +1,124 @@
+package backup
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/logger"
+)
+
+const (
+	// autoPrefix is the file name prefix of scheduled backups. Only those are rotated.
+	autoPrefix = "auto"
+	// checkInterval is how often the scheduler checks whether a backup is due.
+	checkInterval = 1 * time.Hour
+	// scheduleDelay gives the engine time to settle before the first check.
+	scheduleDelay = 5 * time.Minute
+)
+
+// Info describes a backup archive in the backup directory.
+type Info struct {
+	Name      string `json:"name"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	CreatedAt int64  `json:"createdAt"`
+	Automatic bool   `json:"automatic"`
+}
+
+// List returns the backup archives in the backup directory, newest first.
+func List() ([]Info, error) {
+	dir, err := config.GetBackupDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var backups []Info
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".zip" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Info{
+			Name:      e.Name(),
+			Path:      filepath.Join(dir, e.Name()),
+			Size:      info.Size(),
+			CreatedAt: info.ModTime().Unix(),
+			Automatic: strings.HasPrefix(e.Name(), autoPrefix+"-"),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt > backups[j].CreatedAt })
+	return backups, nil
+}
+
+// StartScheduler starts a goroutine that takes automatic backups according to the backup settings.
+func StartScheduler(db *sql.DB, appLogger logger.Logger) {
+	go func() {
+		time.Sleep(scheduleDelay)
+		for {
+			if err := runScheduled(db, appLogger); err != nil {
+				appLogger.Printf("[Backup] Scheduled backup failed: %v", err)
+			}
+			time.Sleep(checkInterval)
+		}
+	}()
+}
+
+// runScheduled takes an automatic backup if the newest one is older than the configured interval,
+// then deletes automatic backups beyond the configured count.
+func runScheduled(db *sql.DB, appLogger logger.Logger) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	settings := cfg.Backup
+	if settings.IntervalHours <= 0 {
+		return nil
+	}
+
+	backups, err := List()
+	if err != nil {
+		return err
+	}
+	var auto []Info
+	for _, b := range backups {
+		if b.Automatic {
+			auto = append(auto, b)
+		}
+	}
+
+	interval := time.Duration(settings.IntervalHours) * time.Hour
+	if len(auto) == 0 || time.Since(time.Unix(auto[0].CreatedAt, 0)) >= interval {
+		path, err := DefaultPath(autoPrefix)
+		if err != nil {
+			return err
+		}
+		if _, err := Create(db, path); err != nil {
+			return err
+		}
+		appLogger.Printf("[Backup] Created automatic backup %s", filepath.Base(path))
+		auto = append([]Info{{Path: path}}, auto...)
+	}
+
+	if settings.Keep > 0 {
+		for _, old := range auto[min(settings.Keep, len(auto)):] {
+			if err := os.Remove(old.Path); err != nil {
+				appLogger.Printf("[Backup] Could not delete old backup %s: %v", old.Name, err)
+			}
+		}
+	}
+	return nil
+}
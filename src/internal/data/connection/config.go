@@ -26,10 +26,28 @@ func OpenAndConfigureDB() (*sql.DB, error) {
 
 	// Enable Write-Ahead Logging (WAL) mode. WAL allows for higher concurrency by separating read and write operations,
 	// which is beneficial for this application where the daemon is constantly writing and the API server is reading.
-	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000", dbPath))
+	// The modernc driver only applies pragmas passed as _pragma parameters.
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbPath))
 	if err != nil {
 		log.Printf("Error opening database: %v", err)
 		return nil, fmt.Errorf("could not open database: %w", err)
 	}
 	return db, nil
 }
+
+// OpenReadOnlyDB opens the existing database for reading. It fails if the database doesn't exist.
+func OpenReadOnlyDB() (*sql.DB, error) {
+	dbPath, err := config.GetDatabasePath()
+	if err != nil {
+		return nil, fmt.Errorf("could not get database path: %w", err)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro&_pragma=busy_timeout(5000)", dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("could not open database: %w", err)
+	}
+	return db, nil
+}
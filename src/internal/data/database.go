@@ -1,49 +1,82 @@
 package data
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"sync"
+	"time"
 
+	"veda-anchor-engine/src/internal/config"
 	"veda-anchor-engine/src/internal/data/connection"
 	"veda-anchor-engine/src/internal/data/schema"
 	"veda-anchor-engine/src/internal/data/write"
 )
 
+// flushTimeout bounds how long FlushWrites waits for queued writes on shutdown.
+const flushTimeout = 10 * time.Second
+
 var (
 	globalDB *sql.DB
-	dbOnce   sync.Once
+	dbMu     sync.Mutex
 )
 
-// InitDB initializes the database, creating the necessary tables and indexes if they don't exist.
-// This function should be called once on application startup.
+// InitDB initializes the database, applying any pending schema migrations.
+// This function should be called once on application startup. If it fails, it can be called again
+// later, e.g. once a locked or missing database becomes available.
 func InitDB() (*sql.DB, error) {
-	var err error
-	dbOnce.Do(func() {
-		globalDB, err = connection.OpenAndConfigureDB()
-		if err != nil {
-			return
-		}
-
-		if err = schema.CreateSchema(globalDB); err != nil {
-			err = fmt.Errorf("could not create schema: %w", err)
-		}
-
-		go write.StartDatabaseWriter(globalDB)
-	})
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if globalDB != nil {
+		return globalDB, nil
+	}
+
+	db, err := connection.OpenAndConfigureDB()
 	if err != nil {
 		return nil, err
 	}
+
+	if err := migrate(db); err != nil {
+		// Never run against a schema we couldn't migrate (or one that is newer than this build).
+		_ = db.Close()
+		return nil, err
+	}
+
+	globalDB = db
+	write.Start(globalDB)
 	return globalDB, nil
 }
 
-// OpenDB opens a connection to the SQLite database.
-// It does not attempt to create the schema and is intended for clients that only need to read data.
+// migrate applies pending schema migrations, backing up the database first.
+func migrate(db *sql.DB) error {
+	backupDir, err := config.GetBackupDir()
+	if err != nil {
+		return fmt.Errorf("could not get backup directory: %w", err)
+	}
+	if err := schema.Migrate(db, backupDir); err != nil {
+		return fmt.Errorf("could not migrate schema: %w", err)
+	}
+	return nil
+}
+
+// OpenDB opens a read-only connection to the existing SQLite database, for clients that only need
+// to read data. It neither migrates the schema nor starts the writer, and the connection is not the
+// global instance; the caller closes it.
 func OpenDB() (*sql.DB, error) {
-	return InitDB()
+	return connection.OpenReadOnlyDB()
 }
 
 // GetDB returns the global database instance.
 func GetDB() *sql.DB {
+	dbMu.Lock()
+	defer dbMu.Unlock()
 	return globalDB
 }
+
+// FlushWrites waits until all queued writes are committed. It must be called before the database
+// is closed, otherwise whatever is still queued is lost.
+func FlushWrites() error {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	return write.Flush(ctx)
+}
@@ -0,0 +1,307 @@
+package export
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// This package streams raw history out of the database as CSV or JSON Lines.
+//
+// DESIGN DECISION:
+// Rows are written to the output as they are read from the cursor, so an export never holds more
+// than one row in memory. Exports walk a table in id order, which also makes them resumable: a chunked
+// export over IPC just continues after the last exported id, without any state kept in the engine.
+//
+// Column schemas are part of the export format. Columns may be appended in later versions, but never
+// renamed, reordered or removed.
+
+// Supported output formats.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// Column types.
+const (
+	typeInteger = "integer"
+	typeText    = "text"
+)
+
+// Column is a column of an exported dataset.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Dataset is an exportable table.
+type Dataset struct {
+	Name    string   `json:"name"`
+	Columns []Column `json:"columns"`
+	table   string
+	// timeColumn is the Unix timestamp column the time range applies to.
+	timeColumn string
+	// endColumn is set for datasets of periods: the Unix timestamp column where a row's period ends,
+	// timeColumn being where it starts. The time range then selects the rows that overlap it.
+	endColumn string
+	// derived maps the columns that aren't stored as such to the SQL expression computing them.
+	derived map[string]string
+}
+
+// datasets lists the exportable tables. Every dataset starts with the row id.
+var datasets = []Dataset{
+	{
+		Name:       "app_events",
+		table:      "app_events",
+		timeColumn: "start_time",
+		Columns: []Column{
+			{"id", typeInteger}, {"process_name", typeText}, {"pid", typeInteger}, {"parent_process_name", typeText},
+			{"exe_path", typeText}, {"start_time", typeInteger}, {"end_time", typeInteger}, {"commercial_name", typeText},
+			{"parent_pid", typeInteger}, {"parent_exe_path", typeText}, {"parent_instance_key", typeText},
+		},
+	},
+	{
+		Name:       "screen_time",
+		table:      "screen_time",
+		timeColumn: "start_time",
+		endColumn:  "end_time",
+		Columns: []Column{
+			{"id", typeInteger}, {"executable_path", typeText}, {"pid", typeInteger},
+			{"timestamp", typeInteger}, {"duration_seconds", typeInteger},
+			{"process_instance_key", typeText}, {"start_time", typeInteger}, {"end_time", typeInteger}, {"window_title", typeText},
+		},
+		// screen_time used to hold the end of the usage and its duration; they are kept as derived columns.
+		derived: map[string]string{
+			"timestamp":        "end_time",
+			"duration_seconds": "end_time - start_time",
+		},
+	},
+	{
+		Name:       "web_events",
+		table:      "web_events",
+		timeColumn: "timestamp",
+		Columns: []Column{
+			{"id", typeInteger}, {"url", typeText}, {"domain", typeText}, {"timestamp", typeInteger},
+			{"source", typeText}, {"browser", typeText}, {"title", typeText},
+		},
+	},
+	{
+		Name:       "block_events",
+		table:      "block_events",
+		timeColumn: "timestamp",
+		Columns: []Column{
+			{"id", typeInteger}, {"kind", typeText}, {"target", typeText}, {"url", typeText},
+			{"action", typeText}, {"outcome", typeText}, {"timestamp", typeInteger},
+		},
+	},
+}
+
+// Datasets returns the exportable datasets and their column schemas.
+func Datasets() []Dataset {
+	return datasets
+}
+
+// Options selects what is exported.
+type Options struct {
+	Dataset string
+	Format  string
+	// Since and Until restrict the export to a time range; zero values leave it open.
+	Since time.Time
+	Until time.Time
+	// AfterID resumes an export after the row with this id.
+	AfterID int64
+	// Limit caps the number of rows; 0 exports all of them.
+	Limit int
+	// Header writes the CSV header line. It is ignored for NDJSON.
+	Header bool
+}
+
+// Result summarizes an export.
+type Result struct {
+	Rows int64
+	// LastID is the id of the last exported row, or AfterID if nothing was exported.
+	LastID int64
+}
+
+// Write streams the rows selected by opts to w.
+func Write(db *sql.DB, w io.Writer, opts Options) (Result, error) {
+	ds, err := lookup(opts.Dataset)
+	if err != nil {
+		return Result{}, err
+	}
+	enc, err := newEncoder(w, opts.Format, ds.Columns)
+	if err != nil {
+		return Result{}, err
+	}
+
+	names := make([]string, len(ds.Columns))
+	for i, c := range ds.Columns {
+		names[i] = c.Name
+		if expr, ok := ds.derived[c.Name]; ok {
+			names[i] = expr + " AS " + c.Name
+		}
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s WHERE id > ?", strings.Join(names, ", "), ds.table)
+	args := []interface{}{opts.AfterID}
+	if !opts.Since.IsZero() {
+		sinceColumn := ds.timeColumn
+		if ds.endColumn != "" {
+			sinceColumn = ds.endColumn
+		}
+		q += fmt.Sprintf(" AND %s >= ?", sinceColumn)
+		args = append(args, opts.Since.Unix())
+	}
+	if !opts.Until.IsZero() {
+		q += fmt.Sprintf(" AND %s <= ?", ds.timeColumn)
+		args = append(args, opts.Until.Unix())
+	}
+	q += " ORDER BY id"
+	if opts.Limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	if opts.Header {
+		if err := enc.header(); err != nil {
+			return Result{}, err
+		}
+	}
+
+	res := Result{LastID: opts.AfterID}
+	values := make([]interface{}, len(ds.Columns))
+	for i, c := range ds.Columns {
+		if c.Type == typeInteger {
+			values[i] = new(sql.NullInt64)
+		} else {
+			values[i] = new(sql.NullString)
+		}
+	}
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return res, err
+		}
+		if err := enc.row(values); err != nil {
+			return res, err
+		}
+		res.Rows++
+		res.LastID = values[0].(*sql.NullInt64).Int64
+	}
+	if err := rows.Err(); err != nil {
+		return res, err
+	}
+	return res, enc.flush()
+}
+
+func lookup(name string) (Dataset, error) {
+	for _, ds := range datasets {
+		if ds.Name == name {
+			return ds, nil
+		}
+	}
+	return Dataset{}, fmt.Errorf("unknown dataset %q", name)
+}
+
+// encoder writes rows in one of the supported formats.
+type encoder struct {
+	columns []Column
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	record  []string
+	line    []byte
+}
+
+func newEncoder(w io.Writer, format string, columns []Column) (*encoder, error) {
+	e := &encoder{columns: columns, buf: bufio.NewWriter(w)}
+	switch format {
+	case FormatCSV:
+		e.csv = csv.NewWriter(e.buf)
+		e.record = make([]string, len(columns))
+	case FormatNDJSON:
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+	return e, nil
+}
+
+func (e *encoder) header() error {
+	if e.csv == nil {
+		return nil
+	}
+	for i, c := range e.columns {
+		e.record[i] = c.Name
+	}
+	return e.csv.Write(e.record)
+}
+
+// row writes a row of scanned values. NULLs become empty CSV fields or JSON nulls.
+func (e *encoder) row(values []interface{}) error {
+	if e.csv != nil {
+		for i, v := range values {
+			switch v := v.(type) {
+			case *sql.NullInt64:
+				e.record[i] = ""
+				if v.Valid {
+					e.record[i] = strconv.FormatInt(v.Int64, 10)
+				}
+			case *sql.NullString:
+				e.record[i] = v.String
+			}
+		}
+		return e.csv.Write(e.record)
+	}
+
+	// Objects are built by hand so keys follow the column order of the schema.
+	line := e.line[:0]
+	line = append(line, '{')
+	for i, v := range values {
+		if i > 0 {
+			line = append(line, ',')
+		}
+		line = strconv.AppendQuote(line, e.columns[i].Name)
+		line = append(line, ':')
+		switch v := v.(type) {
+		case *sql.NullInt64:
+			if v.Valid {
+				line = strconv.AppendInt(line, v.Int64, 10)
+			} else {
+				line = append(line, "null"...)
+			}
+		case *sql.NullString:
+			if v.Valid {
+				b, err := json.Marshal(v.String)
+				if err != nil {
+					return err
+				}
+				line = append(line, b...)
+			} else {
+				line = append(line, "null"...)
+			}
+		}
+	}
+	line = append(line, '}', '\n')
+	e.line = line
+	_, err := e.buf.Write(line)
+	return err
+}
+
+func (e *encoder) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.buf.Flush()
+}
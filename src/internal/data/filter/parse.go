@@ -0,0 +1,212 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+// This package implements the filter language of the history search, e.g.
+//
+//	app:chrome.exe after:2026-10-01 before:yesterday -domain:google.com
+//
+// A query is a list of terms that must all match. A term is either free text or field:value, and a
+// leading "-" negates it. Values containing spaces are written in double quotes.
+//
+// DESIGN DECISION:
+// Queries are parsed completely before any SQL is built, and every error carries the character range
+// it refers to, so the UI can underline the offending term. The planner then turns the parsed terms into
+// one parameterized statement per event table; user input only ever reaches SQL as a bound argument.
+
+// Fields of the filter language.
+const (
+	FieldApp    = "app"
+	FieldPath   = "path"
+	FieldDomain = "domain"
+	FieldURL    = "url"
+	FieldTitle  = "title"
+	FieldPID    = "pid"
+	FieldAfter  = "after"
+	FieldBefore = "before"
+	FieldIn     = "in"
+)
+
+// knownFields lists the fields a term may use.
+var knownFields = map[string]bool{
+	FieldApp: true, FieldPath: true, FieldDomain: true, FieldURL: true, FieldTitle: true,
+	FieldPID: true, FieldAfter: true, FieldBefore: true, FieldIn: true,
+}
+
+// SyntaxError is an error in a filter query. Positions are 0-based character offsets into the query.
+type SyntaxError struct {
+	Pos     int    `json:"pos"`
+	End     int    `json:"end"`
+	Message string `json:"message"`
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s (at position %d)", e.Message, e.Pos)
+}
+
+// ErrorDetail returns the error itself, so the IPC response can carry its position.
+func (e *SyntaxError) ErrorDetail() interface{} {
+	return e
+}
+
+// Term is a single condition of a query.
+type Term struct {
+	// Field is empty for free text.
+	Field   string
+	Value   string
+	Negated bool
+	// Pos and End delimit the term in the query.
+	Pos int
+	End int
+
+	time time.Time
+	pid  int64
+}
+
+// Query is a parsed filter query.
+type Query struct {
+	Terms []Term
+}
+
+// Parse parses a filter query.
+func Parse(input string) (*Query, error) {
+	p := parser{input: []rune(input)}
+	q := &Query{}
+	for {
+		p.skipSpace()
+		if p.pos == len(p.input) {
+			return q, nil
+		}
+		t, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		q.Terms = append(q.Terms, t)
+	}
+}
+
+type parser struct {
+	input []rune
+	pos   int
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+func (p *parser) atEnd() bool {
+	return p.pos == len(p.input) || unicode.IsSpace(p.input[p.pos])
+}
+
+func (p *parser) errorf(pos, end int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, End: end, Message: fmt.Sprintf(format, args...)}
+}
+
+// term parses ['-'] (field ':' value | value).
+func (p *parser) term() (Term, error) {
+	t := Term{Pos: p.pos}
+	if p.input[p.pos] == '-' {
+		t.Negated = true
+		p.pos++
+		if p.atEnd() {
+			return t, p.errorf(t.Pos, p.pos, `"-" must be followed by a term`)
+		}
+	}
+
+	if p.input[p.pos] != '"' {
+		// Look ahead for a field name.
+		start := p.pos
+		end := start
+		for end < len(p.input) && !unicode.IsSpace(p.input[end]) && p.input[end] != ':' && p.input[end] != '"' {
+			end++
+		}
+		if end < len(p.input) && p.input[end] == ':' {
+			name := strings.ToLower(string(p.input[start:end]))
+			if name == "" {
+				return t, p.errorf(start, end+1, "missing field name before \":\"")
+			}
+			if !knownFields[name] {
+				return t, p.errorf(start, end, "unknown field %q; put text containing \":\" in quotes", name)
+			}
+			t.Field = name
+			p.pos = end + 1
+			if p.atEnd() {
+				return t, p.errorf(start, p.pos, "missing value for %q", name)
+			}
+		}
+	}
+
+	valuePos := p.pos
+	value, err := p.value()
+	if err != nil {
+		return t, err
+	}
+	t.Value = value
+	t.End = p.pos
+	if err := t.check(valuePos, p.pos); err != nil {
+		return t, err
+	}
+	return t, nil
+}
+
+// value parses a quoted or unquoted value.
+func (p *parser) value() (string, error) {
+	start := p.pos
+	if p.input[p.pos] == '"' {
+		p.pos++
+		for p.pos < len(p.input) && p.input[p.pos] != '"' {
+			p.pos++
+		}
+		if p.pos == len(p.input) {
+			return "", p.errorf(start, p.pos, "unterminated quote")
+		}
+		p.pos++
+		value := string(p.input[start+1 : p.pos-1])
+		if strings.TrimSpace(value) == "" {
+			return "", p.errorf(start, p.pos, "empty value")
+		}
+		if !p.atEnd() {
+			return "", p.errorf(p.pos, p.pos+1, "expected a space after the closing quote")
+		}
+		return value, nil
+	}
+
+	for !p.atEnd() {
+		if p.input[p.pos] == '"' {
+			return "", p.errorf(p.pos, p.pos+1, "unexpected quote")
+		}
+		p.pos++
+	}
+	return string(p.input[start:p.pos]), nil
+}
+
+// check validates the value of a term; pos and end delimit the value in the query.
+func (t *Term) check(pos, end int) error {
+	var err error
+	switch t.Field {
+	case FieldAfter, FieldBefore:
+		t.time, err = repository.ParseTime(t.Value)
+		if err != nil {
+			return &SyntaxError{Pos: pos, End: end, Message: fmt.Sprintf("invalid date %q", t.Value)}
+		}
+	case FieldPID:
+		t.pid, err = strconv.ParseInt(t.Value, 10, 64)
+		if err != nil || t.pid < 0 {
+			return &SyntaxError{Pos: pos, End: end, Message: fmt.Sprintf("invalid process id %q", t.Value)}
+		}
+	case FieldIn:
+		if _, ok := kindAliases[strings.ToLower(t.Value)]; !ok {
+			return &SyntaxError{Pos: pos, End: end, Message: fmt.Sprintf("unknown event type %q; use apps, screen or web", t.Value)}
+		}
+	}
+	return nil
+}
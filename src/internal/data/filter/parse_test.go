@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Term
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"chrome", []Term{{Value: "chrome", Pos: 0, End: 6}}},
+		{"app:chrome.exe", []Term{{Field: FieldApp, Value: "chrome.exe", Pos: 0, End: 14}}},
+		{"APP:chrome", []Term{{Field: FieldApp, Value: "chrome", Pos: 0, End: 10}}},
+		{"-domain:google.com", []Term{{Field: FieldDomain, Value: "google.com", Negated: true, Pos: 0, End: 18}}},
+		{`title:"Pull requests"`, []Term{{Field: FieldTitle, Value: "Pull requests", Pos: 0, End: 21}}},
+		{`"a:b"`, []Term{{Value: "a:b", Pos: 0, End: 5}}},
+		{`-"two words"`, []Term{{Value: "two words", Negated: true, Pos: 0, End: 12}}},
+		{"a-b", []Term{{Value: "a-b", Pos: 0, End: 3}}},
+		{"pid:42 in:web", []Term{
+			{Field: FieldPID, Value: "42", Pos: 0, End: 6},
+			{Field: FieldIn, Value: "web", Pos: 7, End: 13},
+		}},
+		{"  app:a   -path:b  ", []Term{
+			{Field: FieldApp, Value: "a", Pos: 2, End: 7},
+			{Field: FieldPath, Value: "b", Negated: true, Pos: 10, End: 17},
+		}},
+		{"after:2026-10-01T00:00:00Z", []Term{{Field: FieldAfter, Value: "2026-10-01T00:00:00Z", Pos: 0, End: 26}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			var got []Term
+			for _, term := range q.Terms {
+				term.time, term.pid = time.Time{}, 0
+				got = append(got, term)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		pos, end int
+	}{
+		// Dangling "-".
+		{"-", 0, 1},
+		{"app:chrome -", 11, 12},
+		{"- app:chrome", 0, 1},
+		// Unknown and missing field names.
+		{"foo:bar", 0, 3},
+		{"chrome -foo:bar", 8, 11},
+		{":bar", 0, 1},
+		// Missing values.
+		{"app:", 0, 4},
+		{"app: chrome", 0, 4},
+		{"-domain:", 1, 8},
+		// Quotes.
+		{`"abc`, 0, 4},
+		{`title:"abc`, 6, 10},
+		{`""`, 0, 2},
+		{`"  "`, 0, 4},
+		{`"a"b`, 3, 4},
+		{`ab"c`, 2, 3},
+		{`app:a"b`, 5, 6},
+		// Invalid dates, process ids and event types.
+		{"after:notadate", 6, 14},
+		{"chrome before:someday", 14, 21},
+		{"pid:abc", 4, 7},
+		{"pid:-1", 4, 6},
+		{"pid:1.5", 4, 7},
+		{"in:files", 3, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) error = %v, want a *SyntaxError", tt.input, err)
+			}
+			if syntaxErr.Pos != tt.pos || syntaxErr.End != tt.end {
+				t.Errorf("Parse(%q) error at [%d, %d), want [%d, %d): %s",
+					tt.input, syntaxErr.Pos, syntaxErr.End, tt.pos, tt.end, syntaxErr.Message)
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	after := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+	const (
+		appSelect    = "SELECT id, start_time, process_name, exe_path, commercial_name, NULL FROM app_events WHERE 1=1"
+		appOrder     = " ORDER BY start_time DESC, id DESC LIMIT ?"
+		screenSelect = "SELECT id, start_time, executable_path, executable_path, window_title, end_time - start_time FROM screen_time WHERE 1=1"
+		screenOrder  = " ORDER BY start_time DESC, id DESC LIMIT ?"
+		webSelect    = "SELECT id, timestamp, domain, url, title, NULL FROM web_events WHERE 1=1"
+		webOrder     = " ORDER BY timestamp DESC, id DESC LIMIT ?"
+	)
+
+	tests := []struct {
+		input string
+		want  []Statement
+	}{
+		{"", []Statement{
+			{Kind: KindApp, SQL: appSelect + appOrder, Args: []interface{}{10}},
+			{Kind: KindScreen, SQL: screenSelect + screenOrder, Args: []interface{}{10}},
+			{Kind: KindWeb, SQL: webSelect + webOrder, Args: []interface{}{10}},
+		}},
+		{"app:chrome -pid:42 after:2026-10-01T00:00:00Z", []Statement{
+			{
+				Kind: KindApp,
+				SQL:  appSelect + ` AND process_name LIKE ? ESCAPE '\' AND NOT IFNULL(pid = ?, 0) AND start_time >= ?` + appOrder,
+				Args: []interface{}{"%chrome%", int64(42), after, 10},
+			},
+			{
+				Kind: KindScreen,
+				SQL:  screenSelect + ` AND executable_path LIKE ? ESCAPE '\' AND NOT IFNULL(pid = ?, 0) AND start_time >= ?` + screenOrder,
+				Args: []interface{}{"%chrome%", int64(42), after, 10},
+			},
+		}},
+		{`domain:Example.COM "50%_off" before:2026-10-01T00:00:00Z`, []Statement{
+			{
+				Kind: KindWeb,
+				SQL: webSelect + ` AND (domain = ? OR substr(domain, -length(?) - 1) = '.' || ?)` +
+					` AND (url LIKE ? ESCAPE '\' OR title LIKE ? ESCAPE '\') AND timestamp < ?` + webOrder,
+				Args: []interface{}{"example.com", "example.com", "example.com", `%50\%\_off%`, `%50\%\_off%`, after, 10},
+			},
+		}},
+		{"title:inbox -domain:mail.com", []Statement{
+			{Kind: KindApp, SQL: appSelect + ` AND commercial_name LIKE ? ESCAPE '\'` + appOrder, Args: []interface{}{"%inbox%", 10}},
+			{Kind: KindScreen, SQL: screenSelect + ` AND window_title LIKE ? ESCAPE '\'` + screenOrder, Args: []interface{}{"%inbox%", 10}},
+			{
+				Kind: KindWeb,
+				SQL:  webSelect + ` AND title LIKE ? ESCAPE '\' AND NOT IFNULL((domain = ? OR substr(domain, -length(?) - 1) = '.' || ?), 0)` + webOrder,
+				Args: []interface{}{"%inbox%", "mail.com", "mail.com", "mail.com", 10},
+			},
+		}},
+		{`path:C:\Apps`, []Statement{
+			{Kind: KindApp, SQL: appSelect + ` AND exe_path LIKE ? ESCAPE '\'` + appOrder, Args: []interface{}{`%C:\\Apps%`, 10}},
+			{Kind: KindScreen, SQL: screenSelect + ` AND executable_path LIKE ? ESCAPE '\'` + screenOrder, Args: []interface{}{`%C:\\Apps%`, 10}},
+		}},
+		{"notes in:screen", []Statement{
+			{
+				Kind: KindScreen,
+				SQL:  screenSelect + ` AND (executable_path LIKE ? ESCAPE '\' OR window_title LIKE ? ESCAPE '\')` + screenOrder,
+				Args: []interface{}{"%notes%", "%notes%", 10},
+			},
+		}},
+		{"-in:apps -in:sites", []Statement{
+			{Kind: KindScreen, SQL: screenSelect + screenOrder, Args: []interface{}{10}},
+		}},
+		{"in:apps in:web", []Statement{
+			{Kind: KindApp, SQL: appSelect + appOrder, Args: []interface{}{10}},
+			{Kind: KindWeb, SQL: webSelect + webOrder, Args: []interface{}{10}},
+		}},
+		{"in:web -in:web", nil},
+		{"url:example in:apps", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			q, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			got := q.Plan(10)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Plan(%q) returned %d statements, want %d: %+v", tt.input, len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i].Kind != tt.want[i].Kind || got[i].SQL != tt.want[i].SQL {
+					t.Errorf("Plan(%q)[%d] = %s: %s\nwant %s: %s", tt.input, i, got[i].Kind, got[i].SQL, tt.want[i].Kind, tt.want[i].SQL)
+				}
+				if !reflect.DeepEqual(got[i].Args, tt.want[i].Args) {
+					t.Errorf("Plan(%q)[%d] args = %#v, want %#v", tt.input, i, got[i].Args, tt.want[i].Args)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package filter
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+// Event kinds a query runs against.
+const (
+	KindApp    = "app"
+	KindScreen = "screen"
+	KindWeb    = "web"
+)
+
+// kindAliases maps the values accepted by in: to event kinds.
+var kindAliases = map[string]string{
+	"app": KindApp, "apps": KindApp,
+	"screen": KindScreen, "screentime": KindScreen,
+	"web": KindWeb, "site": KindWeb, "sites": KindWeb,
+}
+
+// Result is an event matching a filter query.
+type Result struct {
+	Kind      string `json:"kind"`
+	ID        int64  `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	// Name is the process name or the domain.
+	Name string `json:"name"`
+	// Detail is the executable path or the URL.
+	Detail string `json:"detail"`
+	// Title is the commercial name of an application, the window title of screen time or the title of a page.
+	Title           string `json:"title,omitempty"`
+	DurationSeconds int64  `json:"durationSeconds,omitempty"`
+}
+
+// Statement is the SQL that runs a query against one event table.
+type Statement struct {
+	Kind string
+	SQL  string
+	Args []interface{}
+}
+
+// condition builds the SQL condition of a field for one table. The value is bound to every "?".
+type condition struct {
+	sql  string
+	like bool
+}
+
+// table describes how the fields of the language map to an event table.
+type table struct {
+	kind       string
+	from       string
+	timeColumn string
+	// columns selects id, timestamp, name, detail, title and duration, in this order.
+	columns    string
+	conditions map[string]condition
+}
+
+var tables = []table{
+	{
+		kind:       KindApp,
+		from:       "app_events",
+		timeColumn: "start_time",
+		columns:    "id, start_time, process_name, exe_path, commercial_name, NULL",
+		conditions: map[string]condition{
+			FieldApp:   {sql: `process_name LIKE ? ESCAPE '\'`, like: true},
+			FieldPath:  {sql: `exe_path LIKE ? ESCAPE '\'`, like: true},
+			FieldTitle: {sql: `commercial_name LIKE ? ESCAPE '\'`, like: true},
+			FieldPID:   {sql: "pid = ?"},
+			"":         {sql: `(process_name LIKE ? ESCAPE '\' OR exe_path LIKE ? ESCAPE '\' OR commercial_name LIKE ? ESCAPE '\')`, like: true},
+		},
+	},
+	{
+		// screen_time only has the executable path, so app: matches anywhere in it. title: matches the
+		// window title, which is only recorded if title capture is enabled.
+		kind:       KindScreen,
+		from:       "screen_time",
+		timeColumn: "start_time",
+		columns:    "id, start_time, executable_path, executable_path, window_title, end_time - start_time",
+		conditions: map[string]condition{
+			FieldApp:   {sql: `executable_path LIKE ? ESCAPE '\'`, like: true},
+			FieldPath:  {sql: `executable_path LIKE ? ESCAPE '\'`, like: true},
+			FieldTitle: {sql: `window_title LIKE ? ESCAPE '\'`, like: true},
+			FieldPID:   {sql: "pid = ?"},
+			"":         {sql: `(executable_path LIKE ? ESCAPE '\' OR window_title LIKE ? ESCAPE '\')`, like: true},
+		},
+	},
+	{
+		kind:       KindWeb,
+		from:       "web_events",
+		timeColumn: "timestamp",
+		columns:    "id, timestamp, domain, url, title, NULL",
+		conditions: map[string]condition{
+			// A domain matches itself and its subdomains.
+			FieldDomain: {sql: "(domain = ? OR substr(domain, -length(?) - 1) = '.' || ?)"},
+			FieldURL:    {sql: `url LIKE ? ESCAPE '\'`, like: true},
+			FieldTitle:  {sql: `title LIKE ? ESCAPE '\'`, like: true},
+			"":          {sql: `(url LIKE ? ESCAPE '\' OR title LIKE ? ESCAPE '\')`, like: true},
+		},
+	},
+}
+
+// Plan returns the statements that run the query, newest events first and at most limit rows each.
+// Tables that a term rules out are left out: a positive condition on a field a table doesn't have,
+// such as domain: for application events, can't match any of its rows. A negated one matches all of them.
+func (q *Query) Plan(limit int) []Statement {
+	var statements []Statement
+	for _, tbl := range tables {
+		if !q.includes(tbl.kind) {
+			continue
+		}
+		stmt, ok := q.plan(tbl, limit)
+		if ok {
+			statements = append(statements, stmt)
+		}
+	}
+	return statements
+}
+
+// includes applies the in: terms.
+func (q *Query) includes(kind string) bool {
+	restricted, listed := false, false
+	for _, t := range q.Terms {
+		if t.Field != FieldIn {
+			continue
+		}
+		match := kindAliases[strings.ToLower(t.Value)] == kind
+		if t.Negated {
+			if match {
+				return false
+			}
+			continue
+		}
+		restricted = true
+		listed = listed || match
+	}
+	return !restricted || listed
+}
+
+func (q *Query) plan(tbl table, limit int) (Statement, bool) {
+	where := []string{"1=1"}
+	var args []interface{}
+	for _, t := range q.Terms {
+		var cond string
+		var condArgs []interface{}
+		switch t.Field {
+		case FieldIn:
+			continue
+		case FieldAfter:
+			cond, condArgs = tbl.timeColumn+" >= ?", []interface{}{t.time.Unix()}
+		case FieldBefore:
+			cond, condArgs = tbl.timeColumn+" < ?", []interface{}{t.time.Unix()}
+		default:
+			c, ok := tbl.conditions[t.Field]
+			if !ok {
+				if t.Negated {
+					continue
+				}
+				return Statement{}, false
+			}
+			var value interface{} = t.Value
+			if t.Field == FieldPID {
+				value = t.pid
+			} else if c.like {
+				value = "%" + escapeLike(t.Value) + "%"
+			} else if t.Field == FieldDomain {
+				value = strings.ToLower(t.Value)
+			}
+			cond = c.sql
+			for range strings.Count(c.sql, "?") {
+				condArgs = append(condArgs, value)
+			}
+		}
+		if t.Negated {
+			// NULL columns don't match a condition, so they do match its negation.
+			cond = "NOT IFNULL(" + cond + ", 0)"
+		}
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s DESC, id DESC LIMIT ?",
+		tbl.columns, tbl.from, strings.Join(where, " AND "), tbl.timeColumn)
+	return Statement{Kind: tbl.kind, SQL: query, Args: append(args, limit)}, true
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Search runs a filter query and returns the newest matching events of all tables.
+func Search(db *sql.DB, input string, limit int) ([]Result, error) {
+	q, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = repository.DefaultPageSize
+	}
+	limit = min(limit, repository.MaxPageSize)
+
+	results := []Result{}
+	for _, stmt := range q.Plan(limit) {
+		rows, err := run(db, stmt)
+		if err != nil {
+			return nil, fmt.Errorf("could not search %s events: %w", stmt.Kind, err)
+		}
+		results = append(results, rows...)
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Timestamp > results[j].Timestamp })
+	return results[:min(limit, len(results))], nil
+}
+
+func run(db *sql.DB, stmt Statement) ([]Result, error) {
+	rows, err := db.Query(stmt.SQL, stmt.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []Result
+	for rows.Next() {
+		r := Result{Kind: stmt.Kind}
+		var name, detail, title sql.NullString
+		var duration sql.NullInt64
+		if err := rows.Scan(&r.ID, &r.Timestamp, &name, &detail, &title, &duration); err != nil {
+			return nil, err
+		}
+		r.Name, r.Detail, r.Title, r.DurationSeconds = name.String, detail.String, title.String, duration.Int64
+		if stmt.Kind == KindScreen {
+			// Name the application after the executable, like app events do.
+			r.Name = r.Name[strings.LastIndexAny(r.Name, `\/`)+1:]
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -15,7 +15,9 @@ import (
 // ClearAppHistory deletes all records related to application usage.
 // This affects the following tables:
 // 1. app_events: Contains process start/stop logs.
-// 2. screen_time: Contains foreground window activity logs.
+// 2. screen_time and idle_periods: Contain foreground window activity and idle logs.
+// 3. rollup_app_daily and the app rows of rollup_hourly: Contain the aggregated usage statistics.
+// 4. executables: Contains the catalog of the executables seen running.
 //
 // After running this, all application-related leaderboards and history logs will be empty.
 func ClearAppHistory() {
@@ -24,6 +26,14 @@ func ClearAppHistory() {
 
 	// Delete all screen time data
 	write.EnqueueWrite("DELETE FROM screen_time")
+	write.EnqueueWrite("DELETE FROM idle_periods")
+
+	// Delete the aggregated statistics, which outlive the raw data
+	write.EnqueueWrite("DELETE FROM rollup_app_daily")
+	write.EnqueueWrite("DELETE FROM rollup_hourly WHERE kind = 'app'")
+
+	// Delete the executables catalog, which records when each executable was seen
+	write.EnqueueWrite("DELETE FROM executables")
 
 	// VACUUM is not called here because it is a blocking operation and might be slow.
 	// SQLite will reuse the free space for future inserts.
@@ -33,12 +43,17 @@ func ClearAppHistory() {
 // This affects the following tables:
 // 1. web_events: Contains the actual URL visit logs.
 // 2. web_metadata: Contains cached titles and favicons for domains.
+// 3. rollup_web_daily and the web rows of rollup_hourly: Contain the aggregated visit statistics.
 //
 // After running this, all web-related leaderboards and history logs will be empty.
 func ClearWebHistory() {
 	// Delete all website visit logs
 	write.EnqueueWrite("DELETE FROM web_events")
 
+	// Delete the aggregated statistics, which outlive the raw data
+	write.EnqueueWrite("DELETE FROM rollup_web_daily")
+	write.EnqueueWrite("DELETE FROM rollup_hourly WHERE kind = 'web'")
+
 	// Delete all cached website metadata (titles, icons)
 	write.EnqueueWrite("DELETE FROM web_metadata")
 }
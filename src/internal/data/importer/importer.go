@@ -0,0 +1,311 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/repository"
+	"veda-anchor-engine/src/internal/data/rollup"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// This package imports visits from a browser's own history database into web_events.
+//
+// DESIGN DECISION:
+// Visits are inserted through the write queue in batches, with a single INSERT ... SELECT per batch
+// that skips visits already present (same URL within dedupWindow seconds). Since the writer executes
+// batches in order, every batch sees the rows of the previous ones, which makes re-importing the same
+// file harmless. The ids of the inserted rows are contiguous, so the rollups are updated from exactly
+// the rows that were inserted.
+
+// SourceImport marks web events that were imported rather than logged by the extension.
+const SourceImport = "import"
+
+// Browsers whose history format is understood.
+const (
+	BrowserChromium = "chromium"
+	BrowserFirefox  = "firefox"
+)
+
+const (
+	// batchSize is the number of visits inserted per statement.
+	batchSize = 200
+	// dedupWindow is the distance in seconds within which two visits of the same URL are considered the same.
+	// The extension logs a visit a moment after the browser records it.
+	dedupWindow = 2
+	// chromiumEpochOffset is the number of seconds between 1601-01-01 (Chromium's epoch) and 1970-01-01.
+	chromiumEpochOffset = 11644473600
+)
+
+// ErrImportRunning is returned when an import is started while another one is still running.
+var ErrImportRunning = errors.New("a history import is already running")
+
+// Status describes the progress of the current or last import.
+type Status struct {
+	Running    bool   `json:"running"`
+	Path       string `json:"path"`
+	Browser    string `json:"browser"`
+	Total      int64  `json:"total"`
+	Processed  int64  `json:"processed"`
+	Imported   int64  `json:"imported"`
+	Duplicates int64  `json:"duplicates"`
+	Skipped    int64  `json:"skipped"`
+	StartedAt  int64  `json:"startedAt"`
+	FinishedAt int64  `json:"finishedAt"`
+	Error      string `json:"error"`
+}
+
+var (
+	statusMu sync.Mutex
+	status   Status
+)
+
+// GetStatus returns the progress of the current or last import.
+func GetStatus() Status {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return status
+}
+
+func updateStatus(fn func(s *Status)) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	fn(&status)
+}
+
+// Start imports a copy of a browser history database in the background.
+// browser may be empty to detect the format from the file.
+func Start(db *sql.DB, appLogger logger.Logger, path, browser string) error {
+	statusMu.Lock()
+	if status.Running {
+		statusMu.Unlock()
+		return ErrImportRunning
+	}
+	status = Status{Running: true, Path: path, StartedAt: time.Now().Unix()}
+	statusMu.Unlock()
+
+	go func() {
+		err := run(db, path, browser)
+		updateStatus(func(s *Status) {
+			s.Running = false
+			s.FinishedAt = time.Now().Unix()
+			if err != nil {
+				s.Error = err.Error()
+			}
+		})
+		if err != nil {
+			appLogger.Printf("[Import] Importing %s failed: %v", path, err)
+			return
+		}
+		s := GetStatus()
+		appLogger.Printf("[Import] Imported %d visits from %s (%d duplicates, %d skipped)", s.Imported, s.Browser, s.Duplicates, s.Skipped)
+	}()
+	return nil
+}
+
+// visit is a page visit read from a browser history database.
+type visit struct {
+	url       string
+	title     string
+	timestamp int64
+}
+
+// run performs an import.
+func run(db *sql.DB, path, browser string) error {
+	src, err := openSource(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = src.Close() }()
+
+	detected, err := detectFormat(src)
+	if err != nil {
+		return err
+	}
+	if browser == "" {
+		browser = detected
+	}
+	updateStatus(func(s *Status) { s.Browser = browser })
+
+	countQuery, visitQuery := queries(detected)
+	var total int64
+	if err := src.QueryRow(countQuery).Scan(&total); err != nil {
+		return err
+	}
+	updateStatus(func(s *Status) { s.Total = total })
+
+	rows, err := src.Query(visitQuery)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	batch := make([]visit, 0, batchSize)
+	var seen map[visit]bool
+	for rows.Next() {
+		var url string
+		var title sql.NullString
+		var ts int64
+		if err := rows.Scan(&url, &title, &ts); err != nil {
+			return err
+		}
+		if detected == BrowserChromium {
+			ts = ts/1_000_000 - chromiumEpochOffset
+		} else {
+			ts /= 1_000_000
+		}
+
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			// Internal pages, files, extensions, etc.
+			updateStatus(func(s *Status) { s.Processed++; s.Skipped++ })
+			continue
+		}
+
+		// A batch statement can't see its own rows, so drop repeats within a batch here.
+		if seen == nil {
+			seen = make(map[visit]bool, batchSize)
+		}
+		v := visit{url: url, title: title.String, timestamp: ts}
+		if seen[v] {
+			updateStatus(func(s *Status) { s.Processed++; s.Duplicates++ })
+			continue
+		}
+		seen[v] = true
+
+		batch = append(batch, v)
+		if len(batch) == batchSize {
+			if err := insertBatch(db, batch, browser); err != nil {
+				return err
+			}
+			batch = batch[:0]
+			seen = nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		return insertBatch(db, batch, browser)
+	}
+	return nil
+}
+
+// openSource opens a browser history database read-only. immutable=1 tells SQLite not to expect
+// other writers, so copies that still have a journal or WAL next to them can be read as they are.
+func openSource(path string) (*sql.DB, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite reports a missing file in read-only mode with a misleading error.
+	if _, err := os.Stat(abs); err != nil {
+		return nil, err
+	}
+	// Characters that have a meaning in URI filenames must be escaped.
+	escaped := strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23").Replace(filepath.ToSlash(abs))
+	if !strings.HasPrefix(escaped, "/") {
+		// Windows drive letter paths need a leading slash in a URI.
+		escaped = "/" + escaped
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("file://%s?mode=ro&immutable=1", escaped))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// detectFormat tells Chromium and Firefox history databases apart by their tables.
+func detectFormat(src *sql.DB) (string, error) {
+	var n int
+	if err := src.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'moz_historyvisits'").Scan(&n); err != nil {
+		return "", fmt.Errorf("not a SQLite database: %w", err)
+	}
+	if n > 0 {
+		return BrowserFirefox, nil
+	}
+	if err := src.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('visits', 'urls')").Scan(&n); err != nil {
+		return "", err
+	}
+	if n == 2 {
+		return BrowserChromium, nil
+	}
+	return "", errors.New("not a Chromium or Firefox history database")
+}
+
+// queries returns the count and visit queries of a history format. Visits are read oldest first,
+// with their time in microseconds since the format's epoch.
+func queries(format string) (count, visits string) {
+	if format == BrowserFirefox {
+		return "SELECT COUNT(*) FROM moz_historyvisits",
+			"SELECT p.url, p.title, v.visit_date FROM moz_historyvisits v JOIN moz_places p ON p.id = v.place_id ORDER BY v.visit_date"
+	}
+	return "SELECT COUNT(*) FROM visits",
+		"SELECT u.url, u.title, v.visit_time FROM visits v JOIN urls u ON u.id = v.url ORDER BY v.visit_time"
+}
+
+// insertBatch inserts the visits of a batch that aren't in web_events yet and adds them to the rollups.
+func insertBatch(db *sql.DB, batch []visit, browser string) error {
+	values := make([]string, len(batch))
+	args := make([]interface{}, 0, 2+len(batch)*4)
+	args = append(args, SourceImport, browser)
+	for i, v := range batch {
+		values[i] = "(?, ?, ?, ?)"
+		args = append(args, v.url, repository.ExtractDomain(v.url), v.timestamp, v.title)
+	}
+	q := fmt.Sprintf(`
+		INSERT INTO web_events (url, domain, timestamp, title, source, browser)
+		SELECT v.column1, v.column2, v.column3, NULLIF(v.column4, ''), ?, ?
+		FROM (VALUES %s) AS v
+		WHERE NOT EXISTS (
+			SELECT 1 FROM web_events e
+			WHERE e.timestamp BETWEEN v.column3 - %d AND v.column3 + %d AND e.url = v.column1
+		)
+	`, strings.Join(values, ", "), dedupWindow, dedupWindow)
+
+	res := write.EnqueueWriteWait(context.Background(), q, args...)
+	if res.Err != nil {
+		return res.Err
+	}
+
+	if res.RowsAffected > 0 {
+		if err := addToRollups(db, res.LastInsertID-res.RowsAffected+1, res.LastInsertID); err != nil {
+			return err
+		}
+	}
+	updateStatus(func(s *Status) {
+		s.Processed += int64(len(batch))
+		s.Imported += res.RowsAffected
+		s.Duplicates += int64(len(batch)) - res.RowsAffected
+	})
+	return nil
+}
+
+// addToRollups counts the web events with ids in [firstID, lastID] in the rollups.
+func addToRollups(db *sql.DB, firstID, lastID int64) error {
+	rows, err := db.Query("SELECT domain, timestamp FROM web_events WHERE id BETWEEN ? AND ?", firstID, lastID)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var domain string
+		var ts int64
+		if err := rows.Scan(&domain, &ts); err != nil {
+			return err
+		}
+		rollup.AddWebVisit(domain, time.Unix(ts, 0))
+	}
+	return rows.Err()
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Access request kinds.
+const (
+	AccessKindWeb = "web"
+	AccessKindApp = "app"
+)
+
+// Access request statuses.
+const (
+	AccessStatusPending  = "pending"
+	AccessStatusApproved = "approved"
+	AccessStatusDenied   = "denied"
+)
+
+// AccessRequest is an unblock request and, once decided, its outcome.
+type AccessRequest struct {
+	ID          int64  `json:"id"`
+	Kind        string `json:"kind"`
+	Target      string `json:"target"`
+	URL         string `json:"url,omitempty"`
+	Reason      string `json:"reason"`
+	Status      string `json:"status"`
+	RequestedAt int64  `json:"requestedAt"`
+	DecidedAt   int64  `json:"decidedAt,omitempty"`
+	ExpiresAt   int64  `json:"expiresAt,omitempty"`
+}
+
+// AccessRepository handles all database operations related to access requests and temporary grants.
+type AccessRepository struct {
+	db *sql.DB
+}
+
+// NewAccessRepository creates a new instance of AccessRepository.
+func NewAccessRepository(db *sql.DB) *AccessRepository {
+	return &AccessRepository{db: db}
+}
+
+const accessRequestColumns = "id, kind, target, url, reason, status, requested_at, decided_at, expires_at"
+
+// CreateRequest stores a new pending request and returns its ID.
+// If a request for the same target is already pending, its ID is returned instead of creating a duplicate.
+//
+// The write bypasses the write queue because the caller needs the ID to track the outcome.
+func (r *AccessRepository) CreateRequest(kind, target, url, reason string) (int64, error) {
+	if kind != AccessKindWeb && kind != AccessKindApp {
+		return 0, fmt.Errorf("unknown access request kind: %s", kind)
+	}
+	if target == "" {
+		return 0, fmt.Errorf("access request target is required")
+	}
+
+	var id int64
+	err := r.db.QueryRow("SELECT id FROM access_requests WHERE kind = ? AND target = ? AND status = ?",
+		kind, target, AccessStatusPending).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, err
+	}
+
+	res, err := r.db.Exec("INSERT INTO access_requests (kind, target, url, reason, status, requested_at) VALUES (?, ?, ?, ?, ?, ?)",
+		kind, target, url, reason, AccessStatusPending, time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// GetRequest returns a single request by ID, or nil if it doesn't exist.
+func (r *AccessRepository) GetRequest(id int64) (*AccessRequest, error) {
+	rows, err := r.db.Query("SELECT "+accessRequestColumns+" FROM access_requests WHERE id = ?", id)
+	if err != nil {
+		return nil, err
+	}
+	requests, err := scanAccessRequests(rows)
+	if err != nil || len(requests) == 0 {
+		return nil, err
+	}
+	return &requests[0], nil
+}
+
+// ListRequests returns requests with the given status, newest first. An empty status returns all requests.
+func (r *AccessRepository) ListRequests(status string) ([]AccessRequest, error) {
+	q := "SELECT " + accessRequestColumns + " FROM access_requests"
+	args := []interface{}{}
+	if status != "" {
+		q += " WHERE status = ?"
+		args = append(args, status)
+	}
+	q += " ORDER BY requested_at DESC LIMIT 200"
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAccessRequests(rows)
+}
+
+// GetDecisionsSince returns the requests decided after a cursor, oldest decision first. The cursor is
+// the decision time and ID of the last decision received: decided_at has one-second resolution, so
+// requests decided in the same second are told apart by their ID.
+func (r *AccessRepository) GetDecisionsSince(decidedAt, id int64) ([]AccessRequest, error) {
+	rows, err := r.db.Query("SELECT "+accessRequestColumns+" FROM access_requests WHERE (decided_at, id) > (?, ?) ORDER BY decided_at ASC, id ASC",
+		decidedAt, id)
+	if err != nil {
+		return nil, err
+	}
+	return scanAccessRequests(rows)
+}
+
+// Decide approves or denies a pending request. expiresAt is only meaningful for approvals.
+func (r *AccessRepository) Decide(id int64, status string, expiresAt int64) error {
+	if status != AccessStatusApproved && status != AccessStatusDenied {
+		return fmt.Errorf("invalid access request decision: %s", status)
+	}
+
+	var expires interface{}
+	if status == AccessStatusApproved {
+		expires = expiresAt
+	}
+
+	res, err := r.db.Exec("UPDATE access_requests SET status = ?, decided_at = ?, expires_at = ? WHERE id = ? AND status = ?",
+		status, time.Now().Unix(), expires, id, AccessStatusPending)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("no pending access request with id %d", id)
+	}
+	return nil
+}
+
+// GetActiveGrants returns the targets of the given kind that have an unexpired approval.
+func (r *AccessRepository) GetActiveGrants(kind string) ([]string, error) {
+	rows, err := r.db.Query("SELECT DISTINCT target FROM access_requests WHERE kind = ? AND status = ? AND expires_at > ?",
+		kind, AccessStatusApproved, time.Now().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var targets []string
+	for rows.Next() {
+		var target string
+		if err := rows.Scan(&target); err == nil {
+			targets = append(targets, target)
+		}
+	}
+	return targets, nil
+}
+
+// scanAccessRequests reads all rows into AccessRequest values and closes the rows.
+func scanAccessRequests(rows *sql.Rows) ([]AccessRequest, error) {
+	defer func() { _ = rows.Close() }()
+
+	requests := []AccessRequest{}
+	for rows.Next() {
+		var req AccessRequest
+		var url, reason sql.NullString
+		var decidedAt, expiresAt sql.NullInt64
+		if err := rows.Scan(&req.ID, &req.Kind, &req.Target, &url, &reason, &req.Status, &req.RequestedAt, &decidedAt, &expiresAt); err != nil {
+			continue
+		}
+		req.URL = url.String
+		req.Reason = reason.String
+		req.DecidedAt = decidedAt.Int64
+		req.ExpiresAt = expiresAt.Int64
+		requests = append(requests, req)
+	}
+	return requests, rows.Err()
+}
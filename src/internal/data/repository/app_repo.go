@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"time"
 	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/rollup"
 	"veda-anchor-engine/src/internal/data/write"
 )
 
@@ -26,7 +27,12 @@ func NewAppRepository(db *sql.DB) *AppRepository {
 }
 
 // GetUsageRanking returns the most used applications in a time range.
+// Long and open-ended ranges are answered from the daily rollups.
 func (r *AppRepository) GetUsageRanking(sinceTime, untilTime time.Time) ([]AppUsageItem, error) {
+	if useRollups(sinceTime, untilTime) {
+		return r.getUsageRankingFromRollups(sinceTime, untilTime)
+	}
+
 	q := "SELECT process_name, COUNT(*) as count FROM app_events WHERE 1=1"
 	args := []interface{}{}
 
@@ -63,17 +69,49 @@ func (r *AppRepository) GetUsageRanking(sinceTime, untilTime time.Time) ([]AppUs
 	return results, nil
 }
 
-// GetScreenTimeTotals retrieves today's screen time grouped by application.
-func (r *AppRepository) GetScreenTimeTotals(todayStart int64) ([]ScreenTimeRecord, error) {
-	q := `
-		SELECT executable_path, SUM(duration_seconds) as total_duration
-		FROM screen_time
-		WHERE timestamp >= ?
-		GROUP BY executable_path
-		ORDER BY total_duration DESC
-		LIMIT 10
-	`
-	rows, err := r.db.Query(q, todayStart)
+// getUsageRankingFromRollups returns the most launched applications from rollup_app_daily.
+func (r *AppRepository) getUsageRankingFromRollups(sinceTime, untilTime time.Time) ([]AppUsageItem, error) {
+	q, args := dayRange("SELECT process_name, SUM(launches) as count FROM rollup_app_daily WHERE 1=1", nil, sinceTime, untilTime)
+	q += " GROUP BY process_name HAVING count > 0 ORDER BY count DESC LIMIT 10"
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []AppUsageItem
+	for rows.Next() {
+		var item AppUsageItem
+		if err := rows.Scan(&item.ProcessName, &item.Count); err != nil {
+			continue
+		}
+
+		// Get the most recent exe path for this process name
+		_ = r.db.QueryRow("SELECT exe_path FROM rollup_app_daily WHERE process_name = ? AND launches > 0 ORDER BY day DESC LIMIT 1",
+			item.ProcessName).Scan(&item.ExecutablePath)
+
+		results = append(results, item)
+	}
+	return results, nil
+}
+
+// GetScreenTimeTotals retrieves the screen time in a time range grouped by application.
+// Long and open-ended ranges are answered from the daily rollups.
+func (r *AppRepository) GetScreenTimeTotals(sinceTime, untilTime time.Time) ([]ScreenTimeRecord, error) {
+	var q string
+	var args []interface{}
+	if useRollups(sinceTime, untilTime) {
+		q, args = dayRange("SELECT exe_path, SUM(foreground_seconds) as total_duration FROM rollup_app_daily WHERE foreground_seconds > 0", nil, sinceTime, untilTime)
+		q += " GROUP BY exe_path"
+	} else {
+		duration, durationArgs, where, whereArgs := clipIntervals(sinceTime, untilTime)
+		q = "SELECT executable_path, SUM(" + duration + ") as total_duration FROM screen_time WHERE 1=1" + where + " GROUP BY executable_path"
+		args = append(durationArgs, whereArgs...)
+	}
+	q += " ORDER BY total_duration DESC LIMIT 10"
+
+	rows, err := r.db.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -90,88 +128,229 @@ func (r *AppRepository) GetScreenTimeTotals(todayStart int64) ([]ScreenTimeRecor
 	return results, nil
 }
 
-// GetTotalDayScreenTime returns the absolute total screen time for today.
-func (r *AppRepository) GetTotalDayScreenTime(todayStart int64) (int, error) {
+// GetTotalScreenTime returns the absolute total screen time in a time range.
+// Long and open-ended ranges are answered from the daily rollups.
+func (r *AppRepository) GetTotalScreenTime(sinceTime, untilTime time.Time) (int, error) {
+	var q string
+	var args []interface{}
+	if useRollups(sinceTime, untilTime) {
+		q, args = dayRange("SELECT COALESCE(SUM(foreground_seconds), 0) FROM rollup_app_daily WHERE 1=1", nil, sinceTime, untilTime)
+	} else {
+		duration, durationArgs, where, whereArgs := clipIntervals(sinceTime, untilTime)
+		q = "SELECT COALESCE(SUM(" + duration + "), 0) FROM screen_time WHERE 1=1" + where
+		args = append(durationArgs, whereArgs...)
+	}
+
 	var total int
-	err := r.db.QueryRow(`
-		SELECT COALESCE(SUM(duration_seconds), 0)
-		FROM screen_time
-		WHERE timestamp >= ?
-	`, todayStart).Scan(&total)
+	err := r.db.QueryRow(q, args...).Scan(&total)
 	return total, err
 }
 
-// SearchEvents searches for application events in the database.
-func (r *AppRepository) SearchEvents(queryStr, since, until string) ([][]string, error) {
-	var sinceTime, untilTime time.Time
-	var err error
-
-	if since != "" {
-		sinceTime, err = ParseTime(since)
+// GetUsageByPath returns the launches and foreground time of every executable in a time range.
+// Runs logged without an executable path are not included. Long and open-ended ranges are answered
+// from the daily rollups.
+func (r *AppRepository) GetUsageByPath(sinceTime, untilTime time.Time) ([]PathUsage, error) {
+	if useRollups(sinceTime, untilTime) {
+		q, args := dayRange("SELECT exe_path, MAX(process_name), SUM(launches), SUM(foreground_seconds) FROM rollup_app_daily WHERE exe_path != ''", nil, sinceTime, untilTime)
+		rows, err := r.db.Query(q+" GROUP BY exe_path", args...)
 		if err != nil {
-			return nil, fmt.Errorf("could not parse 'since' time: %w", err)
+			return nil, err
 		}
+		defer func() { _ = rows.Close() }()
+
+		var results []PathUsage
+		for rows.Next() {
+			var item PathUsage
+			if err := rows.Scan(&item.ExecutablePath, &item.ProcessName, &item.Launches, &item.ForegroundSeconds); err != nil {
+				return nil, err
+			}
+			results = append(results, item)
+		}
+		return results, rows.Err()
 	}
 
-	if until != "" {
-		untilTime, err = ParseTime(until)
-		if err != nil {
-			return nil, fmt.Errorf("could not parse 'until' time: %w", err)
+	usage := make(map[string]*PathUsage)
+	var order []string
+	get := func(exePath string) *PathUsage {
+		item, ok := usage[exePath]
+		if !ok {
+			item = &PathUsage{ExecutablePath: exePath}
+			usage[exePath] = item
+			order = append(order, exePath)
+		}
+		return item
+	}
+
+	q := "SELECT exe_path, MAX(process_name), COUNT(*) FROM app_events WHERE exe_path IS NOT NULL AND exe_path != ''"
+	args := []interface{}{}
+	if !sinceTime.IsZero() {
+		q += " AND start_time >= ?"
+		args = append(args, sinceTime.Unix())
+	}
+	if !untilTime.IsZero() {
+		q += " AND start_time <= ?"
+		args = append(args, untilTime.Unix())
+	}
+	rows, err := r.db.Query(q+" GROUP BY exe_path", args...)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var exePath, processName string
+		var launches int
+		if err := rows.Scan(&exePath, &processName, &launches); err != nil {
+			_ = rows.Close()
+			return nil, err
+		}
+		item := get(exePath)
+		item.ProcessName, item.Launches = processName, launches
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	duration, durationArgs, where, whereArgs := clipIntervals(sinceTime, untilTime)
+	rows, err = r.db.Query("SELECT executable_path, SUM("+duration+") as total_duration FROM screen_time WHERE executable_path IS NOT NULL AND executable_path != ''"+where+
+		" GROUP BY executable_path HAVING total_duration > 0", append(durationArgs, whereArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var exePath string
+		var seconds int
+		if err := rows.Scan(&exePath, &seconds); err != nil {
+			return nil, err
+		}
+		get(exePath).ForegroundSeconds = seconds
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	results := make([]PathUsage, 0, len(order))
+	for _, exePath := range order {
+		results = append(results, *usage[exePath])
+	}
+	return results, nil
+}
+
+// GetAppTitleBreakdown returns the screen time of an application in a time range per window title,
+// longest first. Titles are only kept with the raw intervals, so time already pruned by retention is not included.
+func (r *AppRepository) GetAppTitleBreakdown(exePath string, sinceTime, untilTime time.Time) ([]TitleUsage, error) {
+	duration, durationArgs, where, whereArgs := clipIntervals(sinceTime, untilTime)
+	q := "SELECT IFNULL(window_title, ''), SUM(" + duration + ") as total_duration FROM screen_time WHERE executable_path = ? COLLATE NOCASE" + where +
+		" GROUP BY IFNULL(window_title, '') HAVING total_duration > 0 ORDER BY total_duration DESC"
+	args := append(durationArgs, exePath)
+	args = append(args, whereArgs...)
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	results := []TitleUsage{}
+	for rows.Next() {
+		var item TitleUsage
+		if err := rows.Scan(&item.Title, &item.Seconds); err != nil {
+			return nil, err
 		}
+		results = append(results, item)
+	}
+	return results, rows.Err()
+}
+
+// SearchEvents searches for application events in the database.
+// It returns the 100 most recent matches as rows of display strings.
+func (r *AppRepository) SearchEvents(queryStr, since, until string) ([][]string, error) {
+	page, err := r.SearchEventsPage(queryStr, since, until, PageRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var results [][]string
+	for _, e := range page.Events {
+		results = append(results, []string{
+			time.Unix(e.StartTime, 0).Format("2006-01-02 15:04:05"),
+			e.ProcessName,
+			strconv.FormatInt(e.PID, 10),
+			e.ParentProcessName,
+			e.ExePath,
+		})
+	}
+	return results, nil
+}
+
+// SearchEventsPage returns a page of the application events matching a search, ordered by start time.
+func (r *AppRepository) SearchEventsPage(queryStr, since, until string, page PageRequest) (*AppEventPage, error) {
+	page, err := page.normalize()
+	if err != nil {
+		return nil, err
+	}
+	sinceTime, untilTime, err := ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build the SQL query dynamically based on the provided filters.
-	q := "SELECT process_name, pid, parent_process_name, exe_path, start_time, end_time FROM app_events WHERE 1=1"
+	// Build the filter dynamically based on the provided filters.
+	where := "FROM app_events WHERE 1=1"
 	args := make([]interface{}, 0)
 
 	if queryStr != "" {
-		q += " AND (process_name LIKE ? OR parent_process_name LIKE ?)"
+		where += " AND (process_name LIKE ? OR parent_process_name LIKE ?)"
 		likeQuery := "%" + queryStr + "%"
 		args = append(args, likeQuery, likeQuery)
 	}
 
 	if !sinceTime.IsZero() {
-		sinceUnix := sinceTime.Unix()
-		q += " AND (end_time IS NULL OR end_time >= ?)"
-		args = append(args, sinceUnix)
+		where += " AND (end_time IS NULL OR end_time >= ?)"
+		args = append(args, sinceTime.Unix())
 	}
 
 	if !untilTime.IsZero() {
-		untilUnix := untilTime.Unix()
-		q += " AND start_time <= ?"
-		args = append(args, untilUnix)
+		where += " AND start_time <= ?"
+		args = append(args, untilTime.Unix())
 	}
 
-	q += " ORDER BY start_time DESC LIMIT 100"
+	total, estimate, err := countRows(r.db, where, args)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
 
+	q, args, err := page.keyset("SELECT id, process_name, pid, parent_process_name, parent_pid, parent_exe_path, exe_path, commercial_name, start_time, end_time "+where, args, "start_time")
+	if err != nil {
+		return nil, err
+	}
 	rows, err := r.db.Query(q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("database query failed: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var results [][]string
+	events := []AppEvent{}
 	for rows.Next() {
-		var processName, parentProcessName, exePath string
-		var pid int32
-		var startTime, endTime sql.NullInt64
-
-		if err := rows.Scan(&processName, &pid, &parentProcessName, &exePath, &startTime, &endTime); err != nil {
-			continue
+		var e AppEvent
+		var parentProcessName, parentExePath, exePath, commercialName sql.NullString
+		var pid, parentPID, endTime sql.NullInt64
+		if err := rows.Scan(&e.ID, &e.ProcessName, &pid, &parentProcessName, &parentPID, &parentExePath, &exePath, &commercialName, &e.StartTime, &endTime); err != nil {
+			return nil, err
 		}
-
-		startTimeStr := time.Unix(startTime.Int64, 0).Format("2006-01-02 15:04:05")
-
-		results = append(results, []string{
-			startTimeStr,
-			processName,
-			strconv.Itoa(int(pid)),
-			parentProcessName,
-			exePath,
-		})
+		e.PID, e.ParentPID, e.EndTime = pid.Int64, parentPID.Int64, endTime.Int64
+		e.ParentProcessName, e.ParentExePath = parentProcessName.String, parentExePath.String
+		e.ExePath, e.CommercialName = exePath.String, commercialName.String
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return results, nil
+	n, info := page.pageInfo(len(events), func(i int) (int64, int64) {
+		return events[i].StartTime, events[i].ID
+	})
+	info.Total, info.TotalIsEstimate = total, estimate
+	return &AppEventPage{Events: events[:n], PageInfo: info}, nil
 }
 
 // GetBlockedDetails enriches a list of process names with their latest executable paths.
@@ -192,17 +371,90 @@ func (r *AppRepository) GetBlockedDetails(names []string) ([]AppBlockedDetail, e
 	return details, nil
 }
 
-// SaveScreenTime logs a chunk of active screen time for an application.
-func (r *AppRepository) SaveScreenTime(exePath string, seconds int) error {
-	_, err := r.db.Exec("INSERT INTO screen_time (executable_path, duration_seconds, timestamp) VALUES (?, ?, ?)",
-		exePath, seconds, time.Now().Unix())
-	return err
+// ParentProcess identifies the parent of a logged application run.
+type ParentProcess struct {
+	// PID is 0 if the process has no parent.
+	PID uint32
+	// Name, ExePath and Key (the process instance key) are empty if the parent couldn't be resolved,
+	// e.g. because it had already exited.
+	Name    string
+	ExePath string
+	Key     string
 }
 
-// LogAppEvent records an application start event.
-func (r *AppRepository) LogAppEvent(name string, pid uint32, parentName, exePath string, startTime int64, key string) {
-	write.EnqueueWrite("INSERT INTO app_events (process_name, pid, parent_process_name, exe_path, start_time, process_instance_key) VALUES (?, ?, ?, ?, ?, ?)",
-		name, pid, parentName, exePath, startTime, key)
+// LogAppEvent records an application start event. Its commercial name is filled in later through
+// SetCommercialName.
+func (r *AppRepository) LogAppEvent(name string, pid uint32, parent ParentProcess, exePath string, startTime int64, key string) {
+	var parentPID interface{}
+	if parent.PID != 0 {
+		parentPID = parent.PID
+	}
+	write.EnqueueWrite("INSERT INTO app_events (process_name, pid, parent_pid, parent_process_name, parent_exe_path, parent_instance_key, exe_path, start_time, process_instance_key) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		name, pid, parentPID, nullIfEmpty(parent.Name), nullIfEmpty(parent.ExePath), nullIfEmpty(parent.Key), exePath, startTime, key)
+	if exePath != "" {
+		rollup.AddAppLaunch(exePath, name, time.Unix(startTime, 0))
+	}
+}
+
+// SetCommercialName records the commercial name of the application run with a process instance key.
+func (r *AppRepository) SetCommercialName(key, name string) {
+	write.EnqueueWrite("UPDATE app_events SET commercial_name = ? WHERE process_instance_key = ?", name, key)
+}
+
+// maxAncestryDepth bounds the walk up the parent chain.
+const maxAncestryDepth = 32
+
+// GetProcessAncestry returns the process of an application event followed by its parent, grandparent
+// and so on. The walk follows the parents' own events; it ends at the first parent that wasn't logged,
+// which is still included with what is known about it.
+func (r *AppRepository) GetProcessAncestry(eventID int64) ([]ProcessAncestor, error) {
+	var ancestry []ProcessAncestor
+	seen := make(map[int64]bool)
+	for id := eventID; len(ancestry) <= maxAncestryDepth; {
+		process, parent, parentKey, err := r.ancestryStep(id)
+		if err == sql.ErrNoRows && len(ancestry) == 0 {
+			return nil, fmt.Errorf("application event %d not found", eventID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		ancestry = append(ancestry, process)
+		seen[id] = true
+		if parent == nil {
+			break
+		}
+
+		err = sql.ErrNoRows
+		if parentKey != "" {
+			err = r.db.QueryRow("SELECT id FROM app_events WHERE process_instance_key = ? ORDER BY start_time LIMIT 1", parentKey).Scan(&id)
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == sql.ErrNoRows || seen[id] {
+			ancestry = append(ancestry, *parent)
+			break
+		}
+	}
+	return ancestry, nil
+}
+
+// ancestryStep returns the process of an application event and its parent as recorded with the event.
+// parent is nil if the process has no known parent.
+func (r *AppRepository) ancestryStep(eventID int64) (process ProcessAncestor, parent *ProcessAncestor, parentKey string, err error) {
+	var exePath, parentName, parentExePath, key sql.NullString
+	var parentPID sql.NullInt64
+	err = r.db.QueryRow("SELECT id, process_name, pid, exe_path, start_time, parent_pid, parent_process_name, parent_exe_path, parent_instance_key FROM app_events WHERE id = ?", eventID).
+		Scan(&process.EventID, &process.Name, &process.PID, &exePath, &process.StartTime, &parentPID, &parentName, &parentExePath, &key)
+	if err != nil {
+		return process, nil, "", err
+	}
+	process.ExePath = exePath.String
+	if parentPID.Int64 == 0 && parentName.String == "" {
+		return process, nil, "", nil
+	}
+	parent = &ProcessAncestor{PID: parentPID.Int64, Name: parentName.String, ExePath: parentExePath.String}
+	return process, parent, key.String, nil
 }
 
 // CloseAppEvent records an application stop event.
@@ -234,61 +486,3 @@ func (r *AppRepository) GetActiveSessions() ([]ActiveSession, error) {
 	}
 	return sessions, nil
 }
-
-// UpdateScreenTime increments the duration of the most recent screen time record for an application.
-func (r *AppRepository) UpdateScreenTime(exePath string, duration int) {
-	now := time.Now().Unix()
-	write.EnqueueWrite(`
-		UPDATE screen_time 
-		SET duration_seconds = duration_seconds + ?, timestamp = ?
-		WHERE id = (
-			SELECT id FROM screen_time 
-			WHERE executable_path = ? AND timestamp > ?
-			ORDER BY timestamp DESC LIMIT 1
-		)
-	`, duration, now, exePath, now-300)
-}
-
-// EnsureActiveScreenTimeRecord ensures there is a recent screen time record to update.
-func (r *AppRepository) EnsureActiveScreenTimeRecord(exePath string) {
-	now := time.Now().Unix()
-	write.EnqueueWrite(`
-		INSERT INTO screen_time (executable_path, timestamp, duration_seconds)
-		SELECT ?, ?, 1
-		WHERE NOT EXISTS (
-			SELECT 1 FROM screen_time 
-			WHERE executable_path = ? AND timestamp > ?
-			ORDER BY timestamp DESC LIMIT 1
-		)
-	`, exePath, now, exePath, now-300)
-}
-
-// UpdateScreenTimeByPID updates screen time for a process by PID.
-func (r *AppRepository) UpdateScreenTimeByPID(pid uint32, seconds int64) error {
-	now := time.Now().Unix()
-	_, err := r.db.Exec(`
-		UPDATE screen_time 
-		SET duration_seconds = duration_seconds + ?, timestamp = ?
-		WHERE id = (
-			SELECT id FROM screen_time 
-			WHERE pid = ? AND timestamp > ?
-			ORDER BY timestamp DESC LIMIT 1
-		)
-	`, seconds, now, pid, now-300)
-	return err
-}
-
-// ReportActiveApp reports an active app from Agent.
-func (r *AppRepository) ReportActiveApp(pid uint32, exePath string) error {
-	now := time.Now().Unix()
-	_, err := r.db.Exec(`
-		INSERT INTO screen_time (executable_path, pid, timestamp, duration_seconds)
-		SELECT ?, ?, ?, 1
-		WHERE NOT EXISTS (
-			SELECT 1 FROM screen_time 
-			WHERE pid = ? AND timestamp > ?
-			ORDER BY timestamp DESC LIMIT 1
-		)
-	`, exePath, pid, now, pid, now-300)
-	return err
-}
@@ -19,6 +19,21 @@ type ScreenTimeRecord struct {
 	DurationSeconds int
 }
 
+// PathUsage is the launches and foreground time of an executable.
+type PathUsage struct {
+	ExecutablePath    string
+	ProcessName       string
+	Launches          int
+	ForegroundSeconds int
+}
+
+// TitleUsage is the screen time an application spent with a window title.
+type TitleUsage struct {
+	// Title is the normalized window title, or "" for time recorded without one.
+	Title   string `json:"title"`
+	Seconds int64  `json:"seconds"`
+}
+
 // WebMetadata holds the cached metadata for a website.
 type WebMetadata struct {
 	Domain    string `json:"domain"`
@@ -26,3 +41,61 @@ type WebMetadata struct {
 	IconURL   string `json:"iconUrl"`
 	Timestamp int64  `json:"timestamp"`
 }
+
+// FrictionStat summarizes how visitors reacted to friction on a domain.
+type FrictionStat struct {
+	Domain    string `json:"domain"`
+	Action    string `json:"action"`
+	Passed    int    `json:"passed"`
+	Abandoned int    `json:"abandoned"`
+}
+
+// AppEvent is a logged application run.
+type AppEvent struct {
+	ID                int64  `json:"id"`
+	ProcessName       string `json:"processName"`
+	PID               int64  `json:"pid"`
+	ParentProcessName string `json:"parentProcessName"`
+	// ParentPID is 0 if the parent is unknown; ParentExePath is empty if it couldn't be resolved.
+	ParentPID      int64  `json:"parentPid"`
+	ParentExePath  string `json:"parentExePath"`
+	ExePath        string `json:"exePath"`
+	CommercialName string `json:"commercialName"`
+	StartTime      int64  `json:"startTime"`
+	// EndTime is 0 while the application is still running.
+	EndTime int64 `json:"endTime"`
+}
+
+// ProcessAncestor is a process in the ancestry of an application run.
+type ProcessAncestor struct {
+	// EventID is the application event of the process, or 0 if it wasn't logged.
+	EventID int64  `json:"eventId"`
+	PID     int64  `json:"pid"`
+	Name    string `json:"name"`
+	ExePath string `json:"exePath"`
+	// StartTime is 0 if the process wasn't logged.
+	StartTime int64 `json:"startTime"`
+}
+
+// AppEventPage is a page of application events.
+type AppEventPage struct {
+	Events []AppEvent `json:"events"`
+	PageInfo
+}
+
+// WebLogEntry is a logged page visit.
+type WebLogEntry struct {
+	ID        int64  `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Domain    string `json:"domain"`
+	URL       string `json:"url"`
+	Title     string `json:"title"`
+	Source    string `json:"source"`
+	Browser   string `json:"browser"`
+}
+
+// WebLogPage is a page of web log entries.
+type WebLogPage struct {
+	Entries []WebLogEntry `json:"entries"`
+	PageInfo
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// Executable is a version of an executable file in the catalog, identified by path, size and
+// modification time. Times are Unix seconds.
+type Executable struct {
+	ID      int64  `json:"id"`
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	SHA256  string `json:"sha256"`
+	// Signer is the organization of the authenticode signature, or empty if the file isn't signed.
+	Signer         string `json:"signer"`
+	SignatureValid bool   `json:"signatureValid"`
+	ProductName    string `json:"productName"`
+	FileVersion    string `json:"fileVersion"`
+	FirstSeen      int64  `json:"firstSeen"`
+	LastSeen       int64  `json:"lastSeen"`
+}
+
+// ExecutablePage is a page of the executables catalog.
+type ExecutablePage struct {
+	Executables []Executable `json:"executables"`
+	PageInfo
+}
+
+const executableColumns = "id, path, size, mod_time, sha256, signer, signature_valid, product_name, file_version, first_seen, last_seen"
+
+// SaveExecutable adds an inspected executable to the catalog. If the same version is already
+// cataloged, only its last sighting is updated.
+func (r *AppRepository) SaveExecutable(e Executable) {
+	write.EnqueueWrite(`INSERT INTO executables (path, size, mod_time, sha256, signer, signature_valid, product_name, file_version, first_seen, last_seen)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (path, size, mod_time) DO UPDATE SET last_seen = MAX(last_seen, excluded.last_seen)`,
+		e.Path, e.Size, e.ModTime, nullIfEmpty(e.SHA256), nullIfEmpty(e.Signer), e.SignatureValid,
+		nullIfEmpty(e.ProductName), nullIfEmpty(e.FileVersion), e.FirstSeen, e.LastSeen)
+}
+
+// TouchExecutable records that a cataloged version of an executable was seen at a point in time.
+func (r *AppRepository) TouchExecutable(path string, size, modTime int64, at time.Time) {
+	write.EnqueueWrite("UPDATE executables SET last_seen = MAX(last_seen, ?) WHERE path = ? AND size = ? AND mod_time = ?",
+		at.Unix(), path, size, modTime)
+}
+
+// FindExecutable returns the cataloged version of an executable, or nil if it isn't cataloged.
+func (r *AppRepository) FindExecutable(path string, size, modTime int64) (*Executable, error) {
+	row := r.db.QueryRow("SELECT "+executableColumns+" FROM executables WHERE path = ? AND size = ? AND mod_time = ?", path, size, modTime)
+	return scanOptionalExecutable(row)
+}
+
+// GetExecutable returns the most recently seen version of an executable, or nil if it isn't cataloged.
+func (r *AppRepository) GetExecutable(path string) (*Executable, error) {
+	row := r.db.QueryRow("SELECT "+executableColumns+" FROM executables WHERE path = ? ORDER BY last_seen DESC, id DESC LIMIT 1", path)
+	return scanOptionalExecutable(row)
+}
+
+// GetSignedExecutables returns the cataloged versions of executables that have a valid signature and
+// a product name.
+func (r *AppRepository) GetSignedExecutables() ([]Executable, error) {
+	rows, err := r.db.Query("SELECT " + executableColumns + " FROM executables WHERE signature_valid = 1 AND signer IS NOT NULL AND product_name IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var executables []Executable
+	for rows.Next() {
+		e, err := scanExecutable(rows)
+		if err != nil {
+			return nil, err
+		}
+		executables = append(executables, e)
+	}
+	return executables, rows.Err()
+}
+
+// ListExecutablesPage returns a page of the catalog, ordered by last sighting. A non-empty query
+// matches the path, signer and product name, or a SHA-256 prefix.
+func (r *AppRepository) ListExecutablesPage(queryStr string, page PageRequest) (*ExecutablePage, error) {
+	page, err := page.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	where := "FROM executables WHERE 1=1"
+	args := make([]interface{}, 0)
+	if queryStr != "" {
+		where += " AND (path LIKE ? OR signer LIKE ? OR product_name LIKE ? OR sha256 LIKE ?)"
+		likeQuery := "%" + queryStr + "%"
+		args = append(args, likeQuery, likeQuery, likeQuery, strings.ToLower(queryStr)+"%")
+	}
+
+	total, estimate, err := countRows(r.db, where, args)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+
+	q, args, err := page.keyset("SELECT "+executableColumns+" "+where, args, "last_seen")
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("database query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	executables := []Executable{}
+	for rows.Next() {
+		e, err := scanExecutable(rows)
+		if err != nil {
+			return nil, err
+		}
+		executables = append(executables, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	n, info := page.pageInfo(len(executables), func(i int) (int64, int64) {
+		return executables[i].LastSeen, executables[i].ID
+	})
+	info.Total, info.TotalIsEstimate = total, estimate
+	return &ExecutablePage{Executables: executables[:n], PageInfo: info}, nil
+}
+
+// GetUncatalogedPaths returns the executable paths of logged application runs that have no version in the catalog.
+func (r *AppRepository) GetUncatalogedPaths() ([]string, error) {
+	rows, err := r.db.Query(`SELECT DISTINCT exe_path FROM app_events
+		WHERE exe_path IS NOT NULL AND exe_path != '' AND NOT EXISTS (SELECT 1 FROM executables WHERE path = app_events.exe_path)`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, rows.Err()
+}
+
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanExecutable(row rowScanner) (Executable, error) {
+	var e Executable
+	var sha, signer, productName, fileVersion sql.NullString
+	err := row.Scan(&e.ID, &e.Path, &e.Size, &e.ModTime, &sha, &signer, &e.SignatureValid, &productName, &fileVersion, &e.FirstSeen, &e.LastSeen)
+	e.SHA256, e.Signer, e.ProductName, e.FileVersion = sha.String, signer.String, productName.String, fileVersion.String
+	return e, err
+}
+
+func scanOptionalExecutable(row *sql.Row) (*Executable, error) {
+	e, err := scanExecutable(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
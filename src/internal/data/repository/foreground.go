@@ -0,0 +1,285 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/data/rollup"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// Foreground intervals.
+//
+// screen_time holds one row per stretch of time an application spent in the foreground. The agent
+// reports transitions: StartForeground when an application comes to the foreground, StopForeground
+// when it loses it, and ConfirmForeground heartbeats in between. At most one interval is open at a
+// time; it ends when another one starts.
+//
+// The open interval's end_time is the last time the agent confirmed it. If the agent stops reporting
+// (it crashed, or the machine went to sleep), the next report doesn't extend the interval over the
+// silence: it is closed at its last confirmation instead.
+//
+// If window title capture is enabled, each interval also carries the (normalized) title of the window,
+// and a change of title within the same process starts a new interval.
+//
+// Each transition is queued on the writer as one transaction, together with the foreground time it
+// adds to or takes back from the rollups, so intervals and rollups can't drift apart. Transitions
+// are serialized, and each one first waits for the previous ones to be committed, so it reads the
+// state they left.
+
+const (
+	// foregroundSlack is how long an open interval may go unconfirmed and still be extended to the next report.
+	foregroundSlack = 2 * time.Minute
+	// foregroundMergeGap is how long after its interval ended a process instance may come back to the
+	// foreground and continue that interval instead of starting a new one.
+	foregroundMergeGap = 5 * time.Second
+)
+
+// foregroundMu serializes the foreground transitions, which read and then update the open interval.
+var foregroundMu sync.Mutex
+
+// idleForeground is the process that came to the foreground while the user was idle, or nil.
+// It is guarded by foregroundMu.
+var idleForeground *pendingForeground
+
+// pendingForeground is a process in the foreground that has no interval yet.
+type pendingForeground struct {
+	exePath string
+	pid     uint32
+	key     string
+	title   string
+}
+
+// openInterval is the open row of screen_time.
+type openInterval struct {
+	id      int64
+	exePath string
+	pid     uint32
+	key     string
+	title   string
+	start   int64
+	end     int64
+}
+
+// StartForeground records that a process came to the foreground at a point in time. key is the
+// process instance key (PID-StartTime); if empty, it is looked up from the running app events,
+// so a reused PID doesn't continue the interval of an earlier process. title is the normalized window
+// title, or "" if none is recorded.
+// Reporting the process and title that are already in the foreground just confirms them.
+// While the user is idle, no interval starts: the process is remembered and SetActive starts it.
+func (r *AppRepository) StartForeground(pid uint32, exePath, key, title string, at time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		if key == "" {
+			key = r.runningInstanceKey(pid)
+		}
+		idle, err := r.IsIdle()
+		if err != nil {
+			return err
+		}
+		if idle {
+			idleForeground = &pendingForeground{exePath: exePath, pid: pid, key: key, title: title}
+			closeOpenInterval(tx)
+			return nil
+		}
+
+		open, err := r.openInterval()
+		if err != nil {
+			return err
+		}
+		if open != nil && open.pid == pid && (open.key == "" || key == "" || open.key == key) && open.title == title {
+			confirmInterval(tx, open, at)
+			return nil
+		}
+		if open != nil {
+			extendInterval(tx, open, at, true)
+		}
+
+		// Continue the last interval of the same process instance and title if it ended moments ago.
+		var last openInterval
+		var lastExePath, lastKey, lastTitle sql.NullString
+		q := "SELECT id, executable_path, pid, process_instance_key, window_title, end_time FROM screen_time WHERE pid = ? AND end_time >= ? AND IFNULL(window_title, '') = ?"
+		args := []interface{}{pid, at.Add(-foregroundMergeGap).Unix(), title}
+		if key != "" {
+			q += " AND process_instance_key = ?"
+			args = append(args, key)
+		} else {
+			q += " AND executable_path = ?"
+			args = append(args, exePath)
+		}
+		err = r.db.QueryRow(q+" ORDER BY end_time DESC LIMIT 1", args...).Scan(&last.id, &lastExePath, &last.pid, &lastKey, &lastTitle, &last.end)
+		if err == sql.ErrNoRows {
+			insertInterval(tx, exePath, pid, key, title, at)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		last.exePath, last.key, last.title = lastExePath.String, lastKey.String, lastTitle.String
+		tx.Exec("UPDATE screen_time SET is_open = 1 WHERE id = ?", last.id)
+		extendInterval(tx, &last, at, false)
+		return nil
+	})
+}
+
+// StopForeground records that a process left the foreground at a point in time.
+// A pid of 0 closes the open interval whatever its process.
+func (r *AppRepository) StopForeground(pid uint32, at time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		open, err := r.openInterval()
+		if err != nil || open == nil || (pid != 0 && open.pid != pid) {
+			return err
+		}
+		extendInterval(tx, open, at, true)
+		return nil
+	})
+}
+
+// ConfirmForeground records that a process is still in the foreground at a point in time.
+// It is ignored unless the process has the open interval.
+func (r *AppRepository) ConfirmForeground(pid uint32, at time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		open, err := r.openInterval()
+		if err != nil || open == nil || open.pid != pid {
+			return err
+		}
+		confirmInterval(tx, open, at)
+		return nil
+	})
+}
+
+// CloseStaleForeground closes an open interval or idle period that hasn't been confirmed recently,
+// e.g. one left open when the engine stopped. It ends at its last confirmation.
+func (r *AppRepository) CloseStaleForeground() error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		staleBefore := time.Now().Add(-foregroundSlack).Unix()
+		tx.Exec("UPDATE screen_time SET is_open = 0 WHERE is_open = 1 AND end_time < ?", staleBefore)
+		tx.Exec("UPDATE idle_periods SET is_open = 0 WHERE is_open = 1 AND end_time < ?", staleBefore)
+		return nil
+	})
+}
+
+// SplitForeground ends the open interval at lastSeen, the last time the engine observed the system
+// before a gap, and continues it in a fresh interval from resumed on. Foreground time the interval
+// was credited with during the gap is taken back. An interval that already started after the gap is
+// left alone.
+func (r *AppRepository) SplitForeground(lastSeen, resumed time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		open, err := r.openInterval()
+		if err != nil || open == nil || open.start >= resumed.Unix() {
+			return err
+		}
+
+		end := open.end
+		if open.end > lastSeen.Unix() {
+			end = lastSeen.Unix()
+			if resumed.After(lastSeen) && open.exePath != "" {
+				rollup.RemoveForeground(tx, open.exePath, lastSeen, time.Unix(min(open.end, resumed.Unix()), 0))
+			}
+		}
+		tx.Exec("UPDATE screen_time SET end_time = ?, is_open = 0 WHERE id = ?", max(end, open.start), open.id)
+
+		// Time confirmed after the resume stays in the fresh interval. If the clock jumped backwards,
+		// everything up to lastSeen was counted in the old interval already, so the fresh one starts empty.
+		freshEnd := max(open.end, resumed.Unix())
+		if resumed.Before(lastSeen) {
+			freshEnd = resumed.Unix()
+		}
+		tx.Exec("INSERT INTO screen_time (executable_path, pid, process_instance_key, window_title, start_time, end_time, is_open) VALUES (?, ?, ?, ?, ?, ?, 1)",
+			open.exePath, open.pid, nullIfEmpty(open.key), nullIfEmpty(open.title), resumed.Unix(), freshEnd)
+		return nil
+	})
+}
+
+// foregroundTransition runs a foreground transition. It waits until the earlier transitions are
+// committed, so fn reads the state they left, and queues the statements fn adds to tx as one
+// transaction unless fn fails.
+func (r *AppRepository) foregroundTransition(fn func(tx *write.Tx) error) error {
+	foregroundMu.Lock()
+	defer foregroundMu.Unlock()
+
+	if err := write.Flush(context.Background()); err != nil {
+		return err
+	}
+	tx := &write.Tx{}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	write.EnqueueTx(tx)
+	return nil
+}
+
+// openInterval returns the open interval, or nil.
+func (r *AppRepository) openInterval() (*openInterval, error) {
+	var open openInterval
+	var exePath, key, title sql.NullString
+	var pid sql.NullInt64
+	err := r.db.QueryRow("SELECT id, executable_path, pid, process_instance_key, window_title, start_time, end_time FROM screen_time WHERE is_open = 1 ORDER BY end_time DESC LIMIT 1").
+		Scan(&open.id, &exePath, &pid, &key, &title, &open.start, &open.end)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	open.exePath, open.pid, open.key, open.title = exePath.String, uint32(pid.Int64), key.String, title.String
+	return &open, nil
+}
+
+// closeOpenInterval closes the open interval, if any, at its last confirmation.
+func closeOpenInterval(tx *write.Tx) {
+	tx.Exec("UPDATE screen_time SET is_open = 0 WHERE is_open = 1")
+}
+
+// confirmInterval extends the open interval of the process in the foreground to at. If the interval
+// went stale, the process is in the foreground again from at on, in a new interval.
+func confirmInterval(tx *write.Tx, open *openInterval, at time.Time) {
+	if extendInterval(tx, open, at, false) {
+		insertInterval(tx, open.exePath, open.pid, open.key, open.title, at)
+	}
+}
+
+// extendInterval moves the end of an interval to at and closes it if finish is set. An interval that went
+// unconfirmed for longer than foregroundSlack is stale: it is closed at its last confirmation instead,
+// and extendInterval reports true. The added foreground time goes to the rollups.
+func extendInterval(tx *write.Tx, open *openInterval, at time.Time, finish bool) bool {
+	end := time.Unix(open.end, 0)
+	stale := at.Sub(end) > foregroundSlack
+	if stale || !at.After(end) {
+		if finish || stale {
+			tx.Exec("UPDATE screen_time SET is_open = 0 WHERE id = ?", open.id)
+		}
+		return stale
+	}
+
+	isOpen := 1
+	if finish {
+		isOpen = 0
+	}
+	tx.Exec("UPDATE screen_time SET end_time = ?, is_open = ? WHERE id = ?", at.Unix(), isOpen, open.id)
+	if open.exePath != "" {
+		rollup.AddForeground(tx, open.exePath, end, at)
+	}
+	return false
+}
+
+// insertInterval opens a new interval starting at at.
+func insertInterval(tx *write.Tx, exePath string, pid uint32, key, title string, at time.Time) {
+	tx.Exec("INSERT INTO screen_time (executable_path, pid, process_instance_key, window_title, start_time, end_time, is_open) VALUES (?, ?, ?, ?, ?, ?, 1)",
+		exePath, pid, nullIfEmpty(key), nullIfEmpty(title), at.Unix(), at.Unix())
+}
+
+// runningInstanceKey returns the process instance key of the running process with a PID, or "".
+func (r *AppRepository) runningInstanceKey(pid uint32) string {
+	var key sql.NullString
+	_ = r.db.QueryRow("SELECT process_instance_key FROM app_events WHERE pid = ? AND end_time IS NULL ORDER BY start_time DESC LIMIT 1",
+		pid).Scan(&key)
+	return key.String
+}
+
+func nullIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
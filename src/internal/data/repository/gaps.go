@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// Kinds of system gaps.
+const (
+	GapSuspend   = "suspend"
+	GapClockJump = "clock_jump"
+)
+
+// SystemGap is a period the engine couldn't observe, in Unix seconds. End is before Start when the
+// wall clock jumped backwards.
+type SystemGap struct {
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+	Kind  string `json:"kind"`
+}
+
+// RecordSystemGap records a period the engine couldn't observe.
+func (r *AppRepository) RecordSystemGap(start, end time.Time, kind string) {
+	write.EnqueueWrite("INSERT INTO system_gaps (start_time, end_time, kind) VALUES (?, ?, ?)", start.Unix(), end.Unix(), kind)
+}
+
+// GetSystemGaps returns the periods without data that overlap [since, until], oldest first.
+// Backward clock jumps don't leave a period without data, so they are left out.
+func (r *AppRepository) GetSystemGaps(sinceTime, untilTime time.Time) ([]SystemGap, error) {
+	q := "SELECT start_time, end_time, kind FROM system_gaps WHERE end_time > start_time"
+	var args []interface{}
+	if !sinceTime.IsZero() {
+		q += " AND end_time > ?"
+		args = append(args, sinceTime.Unix())
+	}
+	if !untilTime.IsZero() {
+		q += " AND start_time <= ?"
+		args = append(args, untilTime.Unix())
+	}
+
+	rows, err := r.db.Query(q+" ORDER BY start_time", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	gaps := []SystemGap{}
+	for rows.Next() {
+		var gap SystemGap
+		if err := rows.Scan(&gap.Start, &gap.End, &gap.Kind); err != nil {
+			return nil, err
+		}
+		gaps = append(gaps, gap)
+	}
+	return gaps, rows.Err()
+}
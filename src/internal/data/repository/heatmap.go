@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"veda-anchor-engine/src/internal/data/rollup"
+)
+
+// Heatmap holds a value per weekday (indexed by time.Weekday) and hour of day.
+type Heatmap [7][24]int64
+
+// GetForegroundHeatmap sums up the foreground seconds in a time range per weekday and hour of day.
+// app restricts it to an application, given by executable path or process name; empty means all.
+func (r *AppRepository) GetForegroundHeatmap(sinceTime, untilTime time.Time, app string) (*Heatmap, error) {
+	var cond string
+	var args []interface{}
+	if app != "" {
+		app = strings.ToLower(app)
+		// Keys are executable paths; a process name matches the last path element.
+		cond = ` AND (lower(key) = ? OR lower(substr(key, -length(?) - 1)) IN ('\' || ?, '/' || ?))`
+		args = []interface{}{app, app, app, app}
+	}
+	return hourlyHeatmap(r.db, "SUM(seconds)", rollup.KindApp, sinceTime, untilTime, cond, args)
+}
+
+// GetVisitHeatmap counts the visits in a time range per weekday and hour of day.
+// domain restricts it to a domain and its subdomains; empty means all.
+func (r *WebRepository) GetVisitHeatmap(sinceTime, untilTime time.Time, domain string) (*Heatmap, error) {
+	var cond string
+	var args []interface{}
+	if domain != "" {
+		domain = strings.ToLower(domain)
+		cond = ` AND (key = ? OR substr(key, -length(?) - 1) = '.' || ?)`
+		args = []interface{}{domain, domain, domain}
+	}
+	return hourlyHeatmap(r.db, "SUM(count)", rollup.KindWeb, sinceTime, untilTime, cond, args)
+}
+
+// hourlyHeatmap aggregates rollup_hourly rows of a kind by weekday and hour of day in SQL.
+func hourlyHeatmap(db *sql.DB, aggregate, kind string, sinceTime, untilTime time.Time, cond string, condArgs []interface{}) (*Heatmap, error) {
+	q := "SELECT day_of_week, hour_of_day, " + aggregate + " FROM rollup_hourly WHERE kind = ?"
+	args := []interface{}{kind}
+	if !sinceTime.IsZero() {
+		// Hours are attributed to the range by their start.
+		q += " AND hour_start >= ?"
+		args = append(args, rollup.HourStart(sinceTime).Unix())
+	}
+	if !untilTime.IsZero() {
+		q += " AND hour_start <= ?"
+		args = append(args, untilTime.Unix())
+	}
+	q += cond + " GROUP BY day_of_week, hour_of_day"
+	args = append(args, condArgs...)
+
+	rows, err := db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var heatmap Heatmap
+	for rows.Next() {
+		var weekday, hour int
+		var value int64
+		if err := rows.Scan(&weekday, &hour, &value); err != nil {
+			return nil, err
+		}
+		if weekday >= 0 && weekday < 7 && hour >= 0 && hour < 24 {
+			heatmap[weekday][hour] += value
+		}
+	}
+	return &heatmap, rows.Err()
+}
+
+// UsageHeatmap is the usage of a time range by day of week and hour of day.
+// Rows start with the first day of the week of the calendar settings.
+type UsageHeatmap struct {
+	// Weekdays holds the day of week of each row, 0 being Sunday.
+	Weekdays []int `json:"weekdays"`
+	// Seconds holds the foreground seconds per row and hour of day.
+	Seconds [][]int64 `json:"seconds"`
+	// Visits holds the web visits per row and hour of day.
+	Visits [][]int64 `json:"visits"`
+}
+
+// NewUsageHeatmap lays out foreground seconds and visits as rows starting with weekStart.
+// Either heatmap may be nil, which leaves its matrix at zero.
+func NewUsageHeatmap(weekStart time.Weekday, seconds, visits *Heatmap) *UsageHeatmap {
+	h := &UsageHeatmap{
+		Weekdays: make([]int, 7),
+		Seconds:  make([][]int64, 7),
+		Visits:   make([][]int64, 7),
+	}
+	for row := range 7 {
+		weekday := (int(weekStart) + row) % 7
+		h.Weekdays[row] = weekday
+		h.Seconds[row] = make([]int64, 24)
+		h.Visits[row] = make([]int64, 24)
+		if seconds != nil {
+			copy(h.Seconds[row], seconds[weekday][:])
+		}
+		if visits != nil {
+			copy(h.Visits[row], visits[weekday][:])
+		}
+	}
+	return h
+}
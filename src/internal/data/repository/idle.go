@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+	"veda-anchor-engine/src/internal/calendar"
+	"veda-anchor-engine/src/internal/data/rollup"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// Idle periods.
+//
+// While the user is idle, no foreground interval is open, so idle time never counts as screen time.
+// Going idle ends the foreground interval at the last input, taking back the time it was credited
+// with since, and opens an idle period. Becoming active again closes it and resumes the foreground
+// interval of the process that was interrupted, if it is still in the foreground, or starts one for the
+// process that came to the foreground in the meantime.
+
+// DailyIdle is the idle time of a day.
+type DailyIdle struct {
+	Day         string `json:"day"`
+	IdleSeconds int64  `json:"idleSeconds"`
+}
+
+// SetIdle records that the user is idle at a point in time, without input since lastInput.
+// Reporting it again while idle just confirms it, and closes any interval opened in the meantime at
+// its last confirmation.
+func (r *AppRepository) SetIdle(lastInput, at time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		if lastInput.After(at) {
+			lastInput = at
+		}
+		idle, err := r.IsIdle()
+		if err != nil {
+			return err
+		}
+		if idle {
+			tx.Exec("UPDATE idle_periods SET end_time = MAX(end_time, ?) WHERE is_open = 1", at.Unix())
+			closeOpenInterval(tx)
+			return nil
+		}
+		idleForeground = nil
+
+		open, err := r.openInterval()
+		if err != nil {
+			return err
+		}
+		if open != nil {
+			end := min(open.end, max(lastInput.Unix(), open.start))
+			if end < open.end && open.exePath != "" {
+				rollup.RemoveForeground(tx, open.exePath, time.Unix(end, 0), time.Unix(open.end, 0))
+			}
+			tx.Exec("UPDATE screen_time SET end_time = ?, is_open = 0 WHERE id = ?", end, open.id)
+			// Idle time doesn't overlap the screen time it was taken from.
+			lastInput = time.Unix(max(lastInput.Unix(), end), 0)
+		}
+
+		tx.Exec("INSERT INTO idle_periods (start_time, end_time, is_open) VALUES (?, ?, 1)", lastInput.Unix(), at.Unix())
+		return nil
+	})
+}
+
+// SetActive records that the user is active at a point in time. If that ends an idle period, a new
+// interval starts for the process in the foreground: the one that came to the foreground during the
+// idle period, or else the one whose foreground interval the idle period interrupted.
+func (r *AppRepository) SetActive(pid uint32, at time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		var idleStart int64
+		err := r.db.QueryRow("SELECT start_time FROM idle_periods WHERE is_open = 1").Scan(&idleStart)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		tx.Exec("UPDATE idle_periods SET end_time = MAX(end_time, ?), is_open = 0 WHERE is_open = 1", at.Unix())
+
+		pending := idleForeground
+		idleForeground = nil
+		if pending != nil && pending.pid == pid {
+			open, err := r.openInterval()
+			if err != nil || open != nil {
+				return err
+			}
+			insertInterval(tx, pending.exePath, pid, pending.key, pending.title, at)
+			return nil
+		}
+
+		// The interrupted interval is the last one, ended when the idle period started.
+		var exePath, key, title sql.NullString
+		var lastPID sql.NullInt64
+		err = r.db.QueryRow("SELECT executable_path, pid, process_instance_key, window_title FROM screen_time WHERE end_time <= ? ORDER BY end_time DESC, id DESC LIMIT 1",
+			idleStart).Scan(&exePath, &lastPID, &key, &title)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if err == sql.ErrNoRows || uint32(lastPID.Int64) != pid {
+			return nil
+		}
+		open, err := r.openInterval()
+		if err != nil || open != nil {
+			return err
+		}
+		insertInterval(tx, exePath.String, pid, key.String, title.String, at)
+		return nil
+	})
+}
+
+// EndIdle closes the open idle period at a point in time, e.g. the last observation before the machine
+// was suspended.
+func (r *AppRepository) EndIdle(at time.Time) error {
+	return r.foregroundTransition(func(tx *write.Tx) error {
+		idleForeground = nil
+		tx.Exec("UPDATE idle_periods SET end_time = MAX(start_time, MIN(end_time, ?)), is_open = 0 WHERE is_open = 1", at.Unix())
+		return nil
+	})
+}
+
+// IsIdle reports whether an idle period is open.
+func (r *AppRepository) IsIdle() (bool, error) {
+	var idle bool
+	err := r.db.QueryRow("SELECT COUNT(*) > 0 FROM idle_periods WHERE is_open = 1").Scan(&idle)
+	return idle, err
+}
+
+// GetDailyIdleTime returns the idle time of each day between since and until, oldest first.
+// Days follow the calendar settings, and days without idle time are included with zero.
+func (r *AppRepository) GetDailyIdleTime(sinceTime, untilTime time.Time) ([]DailyIdle, error) {
+	cal := calendar.Current()
+	bounds, err := cal.Buckets(calendar.UnitDay, sinceTime, untilTime, maxSeriesBuckets)
+	if err != nil {
+		return nil, err
+	}
+	n := len(bounds) - 1
+
+	days := make([]DailyIdle, n)
+	for i := range n {
+		days[i].Day = cal.Date(bounds[i])
+	}
+
+	rows, err := r.db.Query("SELECT start_time, end_time FROM idle_periods WHERE end_time > ? AND start_time < ? ORDER BY start_time",
+		bounds[0].Unix(), bounds[n].Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var start, end int64
+		if err := rows.Scan(&start, &end); err != nil {
+			return nil, err
+		}
+		// Clip the period to each day it overlaps.
+		for i := range n {
+			from, to := max(start, bounds[i].Unix()), min(end, bounds[i+1].Unix())
+			if to > from {
+				days[i].IdleSeconds += to - from
+			}
+		}
+	}
+	return days, rows.Err()
+}
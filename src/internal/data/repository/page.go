@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Event searches are paginated with keyset cursors rather than offsets.
+//
+// DESIGN DECISION:
+// A cursor holds the (timestamp, id) of the last row of a page, and the next page continues strictly
+// after it in the requested order. Unlike an offset, this stays correct while new events are logged
+// and costs the same on every page. Cursors are opaque to clients so the encoding can change.
+
+// Sort orders of a paginated search.
+const (
+	OrderDesc = "desc"
+	OrderAsc  = "asc"
+)
+
+const (
+	// DefaultPageSize is used when a page request has no limit.
+	DefaultPageSize = 100
+	// MaxPageSize caps the number of rows per page.
+	MaxPageSize = 1000
+	// countCap bounds the rows counted for a total. Larger results report the cap as an estimate.
+	countCap = 10000
+	// cursorVersion prefixes encoded cursors.
+	cursorVersion = "v1"
+)
+
+// ErrInvalidCursor is returned for a cursor that wasn't produced by a previous page.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageRequest selects a page of a search.
+type PageRequest struct {
+	// Cursor is the NextCursor of the previous page, or empty for the first page.
+	Cursor string
+	Limit  int
+	Order  string
+}
+
+// PageInfo describes a returned page.
+type PageInfo struct {
+	// NextCursor continues after this page; it is empty on the last page.
+	NextCursor string `json:"nextCursor"`
+	HasMore    bool   `json:"hasMore"`
+	// Total is the number of rows matching the filters. If TotalIsEstimate is set, there are at least this many.
+	Total           int64 `json:"total"`
+	TotalIsEstimate bool  `json:"totalIsEstimate"`
+}
+
+// cursor is the decoded position of a page boundary.
+type cursor struct {
+	timestamp int64
+	id        int64
+	order     string
+}
+
+func (c cursor) encode() string {
+	raw := fmt.Sprintf("%s:%s:%d:%d", cursorVersion, c.order, c.timestamp, c.id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 4 || parts[0] != cursorVersion {
+		return cursor{}, ErrInvalidCursor
+	}
+	ts, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{timestamp: ts, id: id, order: parts[1]}, nil
+}
+
+// normalize fills in defaults and validates a page request.
+func (p PageRequest) normalize() (PageRequest, error) {
+	switch strings.ToLower(p.Order) {
+	case "", OrderDesc:
+		p.Order = OrderDesc
+	case OrderAsc:
+		p.Order = OrderAsc
+	default:
+		return p, fmt.Errorf("unknown sort order %q", p.Order)
+	}
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageSize
+	}
+	p.Limit = min(p.Limit, MaxPageSize)
+	return p, nil
+}
+
+// keyset appends the cursor condition and the ordering of a page over (timeColumn, id) to q,
+// and fetches one extra row to tell whether there is a next page.
+func (p PageRequest) keyset(q string, args []interface{}, timeColumn string) (string, []interface{}, error) {
+	op, dir := "<", "DESC"
+	if p.Order == OrderAsc {
+		op, dir = ">", "ASC"
+	}
+	if p.Cursor != "" {
+		c, err := decodeCursor(p.Cursor)
+		if err != nil {
+			return "", nil, err
+		}
+		if c.order != p.Order {
+			return "", nil, fmt.Errorf("%w: cursor was issued for %s order", ErrInvalidCursor, c.order)
+		}
+		q += fmt.Sprintf(" AND (%s, id) %s (?, ?)", timeColumn, op)
+		args = append(args, c.timestamp, c.id)
+	}
+	q += fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ?", timeColumn, dir, dir)
+	args = append(args, p.Limit+1)
+	return q, args, nil
+}
+
+// pageInfo trims the extra row fetched by keyset and builds the page info.
+// key returns the (timestamp, id) of the i-th row.
+func (p PageRequest) pageInfo(n int, key func(i int) (int64, int64)) (int, PageInfo) {
+	info := PageInfo{}
+	if n > p.Limit {
+		n = p.Limit
+		ts, id := key(n - 1)
+		info.HasMore = true
+		info.NextCursor = cursor{timestamp: ts, id: id, order: p.Order}.encode()
+	}
+	return n, info
+}
+
+// countRows counts the rows matching a filtered query, up to countCap.
+// where is the FROM ... WHERE part of the query, without the cursor condition.
+func countRows(db *sql.DB, where string, args []interface{}) (int64, bool, error) {
+	var total int64
+	q := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 %s LIMIT %d)", where, countCap+1)
+	if err := db.QueryRow(q, args...).Scan(&total); err != nil {
+		return 0, false, err
+	}
+	if total > countCap {
+		return countCap, true, nil
+	}
+	return total, false, nil
+}
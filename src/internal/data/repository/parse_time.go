@@ -2,51 +2,183 @@ package repository
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+	"veda-anchor-engine/src/internal/calendar"
 )
 
 // ParseTime is a helper function that parses a string into a time.Time object.
-// It supports relative time strings (e.g., "now", "1 hour ago") and various absolute time string formats.
+// It supports relative time strings (see ParsePeriod) and various absolute time string formats.
+// A calendar period such as "yesterday" or "last month" stands for its start.
 func ParseTime(input string) (time.Time, error) {
-	now := time.Now()
-	lowerInput := strings.ToLower(strings.TrimSpace(input))
+	start, _, err := parse(input, time.Now())
+	return start, err
+}
+
+// ParsePeriod parses a time string into the period [start, end) it denotes. Besides absolute times,
+// which denote a single instant (start == end), it understands:
+//
+//	now
+//	N seconds/minutes/hours/days/weeks/months/years ago (also "an hour ago", "1 hour ago")
+//	today, yesterday
+//	this/last day, week, month or year
+//	start of day, week, month or year
+//
+// Days and weeks are delimited by the calendar settings, so with a day start of 4 AM "today" starts
+// at 4 AM, and at 2 AM it is still the previous day.
+func ParsePeriod(input string) (start, end time.Time, err error) {
+	return parse(input, time.Now())
+}
+
+func parse(input string, now time.Time) (time.Time, time.Time, error) {
+	lowerInput := strings.Join(strings.Fields(strings.ToLower(input)), " ")
+	if lowerInput == "" {
+		return time.Time{}, time.Time{}, fmt.Errorf("empty time")
+	}
+	cal := calendar.Current()
+	now = now.In(cal.Location())
 
-	// Handle relative time strings for quick presets.
+	// Handle relative time strings.
 	switch lowerInput {
 	case "now":
-		return now, nil
-	case "1 hour ago":
-		return now.Add(-1 * time.Hour), nil
-	case "24 hours ago":
-		return now.Add(-24 * time.Hour), nil
-	case "7 days ago":
-		return now.AddDate(0, 0, -7), nil
+		return now, now, nil
+	case "today", "this day":
+		start, end := cal.DayWindow(now)
+		return start, end, nil
+	case "yesterday", "last day":
+		end := cal.StartOfDay(now)
+		return cal.AddDays(end, -1), end, nil
+	}
+
+	if unit, ok := strings.CutPrefix(lowerInput, "start of "); ok {
+		start, _, err := period(cal, unit, now, 0)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("could not parse time: %s", input)
+		}
+		return start, start, nil
+	}
+	if unit, ok := strings.CutPrefix(lowerInput, "this "); ok {
+		if start, end, err := period(cal, unit, now, 0); err == nil {
+			return start, end, nil
+		}
+	}
+	if unit, ok := strings.CutPrefix(lowerInput, "last "); ok {
+		if start, end, err := period(cal, unit, now, -1); err == nil {
+			return start, end, nil
+		}
+	}
+	if rest, ok := strings.CutSuffix(lowerInput, " ago"); ok {
+		t, err := ago(rest, now)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("could not parse time %q: %w", input, err)
+		}
+		return t, t, nil
 	}
 
-	// Handle absolute time strings, preferring timezone-aware formats first.
-	// time.RFC3339 is the layout for `date.toISOString()` from the frontend.
+	// Handle absolute time strings. time.RFC3339 is the layout for `date.toISOString()` from the frontend
+	// and carries its own timezone.
+	if parsedTime, err := time.Parse(time.RFC3339, strings.TrimSpace(input)); err == nil {
+		return parsedTime, parsedTime, nil
+	}
+
+	// Formats without timezone info are in the timezone of the calendar settings.
 	layouts := []string{
-		time.RFC3339,
 		"2006-01-02 15:04:05",
 		"2006-01-02T15:04",
 	}
-
 	for _, layout := range layouts {
-		// First, try parsing without assuming a location (for timezone-aware strings).
-		parsedTime, err := time.Parse(layout, input)
+		parsedTime, err := time.ParseInLocation(layout, strings.TrimSpace(input), cal.Location())
 		if err == nil {
-			return parsedTime, nil
+			return parsedTime, parsedTime, nil
 		}
 	}
 
-	// As a fallback, try parsing in the server's local timezone for formats without timezone info.
-	for _, layout := range layouts {
-		parsedTime, err := time.ParseInLocation(layout, input, time.Local)
-		if err == nil {
-			return parsedTime, nil
+	// A date is the whole day.
+	if start, err := cal.ParseDate(strings.TrimSpace(input)); err == nil {
+		return start, cal.AddDays(start, 1), nil
+	}
+
+	return time.Time{}, time.Time{}, fmt.Errorf("could not parse time: %s", input)
+}
+
+// period returns the calendar period of the given unit containing now, shifted by offset periods.
+func period(cal calendar.Calendar, unit string, now time.Time, offset int) (time.Time, time.Time, error) {
+	switch unit {
+	case "day", "today":
+		start := cal.AddDays(cal.StartOfDay(now), offset)
+		return start, cal.AddDays(start, 1), nil
+	case "week":
+		start := cal.AddDays(cal.StartOfWeek(now), 7*offset)
+		return start, cal.AddDays(start, 7), nil
+	case "month":
+		start := cal.AddMonths(cal.StartOfMonth(now), offset)
+		return start, cal.AddMonths(start, 1), nil
+	case "year":
+		start := cal.AddMonths(cal.StartOfYear(now), 12*offset)
+		return start, cal.AddMonths(start, 12), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown period %q", unit)
+}
+
+// ago parses "N units" and returns that long before now. Days and longer units follow the calendar,
+// so "1 day ago" is the same time of day yesterday even across a daylight saving change.
+func ago(s string, now time.Time) (time.Time, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return time.Time{}, fmt.Errorf("expected an amount and a unit")
+	}
+	var n int
+	switch fields[0] {
+	case "a", "an", "one":
+		n = 1
+	default:
+		var err error
+		n, err = strconv.Atoi(fields[0])
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid amount %q", fields[0])
 		}
 	}
 
-	return time.Time{}, fmt.Errorf("could not parse time: %s", input)
+	switch strings.TrimSuffix(fields[1], "s") {
+	case "second", "sec":
+		return now.Add(-time.Duration(n) * time.Second), nil
+	case "minute", "min":
+		return now.Add(-time.Duration(n) * time.Minute), nil
+	case "hour":
+		return now.Add(-time.Duration(n) * time.Hour), nil
+	case "day":
+		return now.AddDate(0, 0, -n), nil
+	case "week":
+		return now.AddDate(0, 0, -7*n), nil
+	case "month":
+		return now.AddDate(0, -n, 0), nil
+	case "year":
+		return now.AddDate(-n, 0, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown unit %q", fields[1])
+}
+
+// ParseTimeRange parses the optional since and until bounds of a time range.
+// A calendar period as until includes the whole period, so since and until "last month" cover last month.
+func ParseTimeRange(since, until string) (sinceTime, untilTime time.Time, err error) {
+	if since != "" {
+		sinceTime, err = ParseTime(since)
+		if err != nil {
+			return sinceTime, untilTime, fmt.Errorf("could not parse 'since' time: %w", err)
+		}
+	}
+	if until != "" {
+		var start, end time.Time
+		start, end, err = ParsePeriod(until)
+		if err != nil {
+			return sinceTime, untilTime, fmt.Errorf("could not parse 'until' time: %w", err)
+		}
+		untilTime = end
+		if end.After(start) {
+			// Ranges include their until bound, at second precision.
+			untilTime = end.Add(-time.Second)
+		}
+	}
+	return sinceTime, untilTime, nil
 }
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"time"
+	"veda-anchor-engine/src/internal/data/rollup"
+)
+
+// rollupThreshold is the range length above which statistics are read from the rollup tables
+// instead of the raw event tables.
+const rollupThreshold = 48 * time.Hour
+
+// useRollups reports whether a statistics query over [since, until] should read the rollups.
+// An open-ended since always does, since raw events may already have been pruned.
+func useRollups(sinceTime, untilTime time.Time) bool {
+	if sinceTime.IsZero() {
+		return true
+	}
+	if untilTime.IsZero() {
+		untilTime = time.Now()
+	}
+	return untilTime.Sub(sinceTime) > rollupThreshold
+}
+
+// dayRange appends conditions restricting a daily rollup query to the days touched by [since, until].
+// Rollups have day granularity, so partial days at either end are counted in full.
+func dayRange(q string, args []interface{}, sinceTime, untilTime time.Time) (string, []interface{}) {
+	if !sinceTime.IsZero() {
+		q += " AND day >= ?"
+		args = append(args, rollup.DayKey(sinceTime))
+	}
+	if !untilTime.IsZero() {
+		q += " AND day <= ?"
+		args = append(args, rollup.DayKey(untilTime))
+	}
+	return q, args
+}
+
+// clipIntervals returns the SQL expression of the seconds a screen_time interval spends within
+// [since, until], and the conditions selecting the intervals that overlap it. Like the other ranges,
+// until is inclusive at second precision; a zero since or until leaves that side open.
+func clipIntervals(sinceTime, untilTime time.Time) (duration string, durationArgs []interface{}, where string, whereArgs []interface{}) {
+	start, end := "start_time", "end_time"
+	if !untilTime.IsZero() {
+		end = "MIN(end_time, ?)"
+		durationArgs = append(durationArgs, untilTime.Unix()+1)
+		where += " AND start_time <= ?"
+		whereArgs = append(whereArgs, untilTime.Unix())
+	}
+	if !sinceTime.IsZero() {
+		start = "MAX(start_time, ?)"
+		durationArgs = append(durationArgs, sinceTime.Unix())
+		where += " AND end_time > ?"
+		whereArgs = append(whereArgs, sinceTime.Unix())
+	}
+	return "MAX(" + end + " - " + start + ", 0)", durationArgs, where, whereArgs
+}
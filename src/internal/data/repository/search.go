@@ -0,0 +1,239 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Full-text search over the event tables, backed by the FTS5 indexes of schema.SearchSchema.
+//
+// DESIGN DECISION:
+// User input is never passed to MATCH as is: FTS5 has its own query syntax, and stray quotes or
+// operators in a search box would turn into syntax errors. Instead, the input is parsed into quoted
+// terms and phrases, so only the supported operators (prefix, phrase, OR) take effect.
+
+// Search hit kinds.
+const (
+	HitKindApp = "app"
+	HitKindWeb = "web"
+)
+
+// Snippet highlight markers. They are control characters so they can't clash with the indexed text;
+// clients split snippets on them to render the matched terms.
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// snippetTokens is the approximate length of a snippet, in tokens.
+const snippetTokens = 12
+
+// ErrEmptySearch is returned for a search query without any term.
+var ErrEmptySearch = errors.New("search query is empty")
+
+// SearchHit is an app or web event matching a full-text search.
+type SearchHit struct {
+	Kind      string `json:"kind"`
+	ID        int64  `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	// Title is the page title or the commercial name of the application.
+	Title       string `json:"title"`
+	URL         string `json:"url,omitempty"`
+	Domain      string `json:"domain,omitempty"`
+	ProcessName string `json:"processName,omitempty"`
+	ExePath     string `json:"exePath,omitempty"`
+	// Snippet is an excerpt of the best matching field, with matches between HighlightStart and HighlightEnd.
+	Snippet string `json:"snippet"`
+	// Score ranks the hit; higher is more relevant.
+	Score float64 `json:"score"`
+}
+
+// matchExpression turns a search query into an FTS5 MATCH expression.
+// Terms must all match, unless separated by OR. A term ending in * matches as a prefix,
+// and text in double quotes matches as a phrase.
+func matchExpression(query string) (string, error) {
+	var parts []string
+	pendingOr := false
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		var token string
+		prefix := false
+		if rest[0] == '"' {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				// An unterminated phrase runs to the end of the query.
+				token, rest = rest[1:], ""
+			} else {
+				token, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.IndexAny(rest, " \t\"")
+			if end < 0 {
+				end = len(rest)
+			}
+			token, rest = rest[:end], rest[end:]
+			if token == "OR" {
+				pendingOr = len(parts) > 0
+				rest = strings.TrimSpace(rest)
+				continue
+			}
+			token, prefix = strings.CutSuffix(token, "*")
+		}
+		rest = strings.TrimSpace(rest)
+
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		term := `"` + strings.ReplaceAll(token, `"`, `""`) + `"`
+		if prefix {
+			term += "*"
+		}
+		if pendingOr {
+			parts[len(parts)-1] += " OR " + term
+			pendingOr = false
+			continue
+		}
+		parts = append(parts, term)
+	}
+	if len(parts) == 0 {
+		return "", ErrEmptySearch
+	}
+	for i, p := range parts {
+		if strings.Contains(p, " OR ") {
+			parts[i] = "(" + p + ")"
+		}
+	}
+	return strings.Join(parts, " AND "), nil
+}
+
+// SearchText returns the web events whose URL or title match a full-text query, best matches first.
+func (r *WebRepository) SearchText(query string, sinceTime, untilTime time.Time, limit int) ([]SearchHit, error) {
+	match, err := matchExpression(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Titles weigh more than URLs, which contain many incidental tokens.
+	q := `
+		SELECT e.id, e.timestamp, e.url, e.domain, e.title,
+			snippet(web_events_fts, -1, ?, ?, '…', ?), bm25(web_events_fts, 1.0, 2.0) AS score
+		FROM web_events_fts JOIN web_events e ON e.id = web_events_fts.rowid
+		WHERE web_events_fts MATCH ?`
+	args := []interface{}{HighlightStart, HighlightEnd, snippetTokens, match}
+	if !sinceTime.IsZero() {
+		q += " AND e.timestamp >= ?"
+		args = append(args, sinceTime.Unix())
+	}
+	if !untilTime.IsZero() {
+		q += " AND e.timestamp <= ?"
+		args = append(args, untilTime.Unix())
+	}
+	q += " ORDER BY score, e.timestamp DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	hits := []SearchHit{}
+	for rows.Next() {
+		h := SearchHit{Kind: HitKindWeb}
+		var title, snippet sql.NullString
+		var bm25 float64
+		if err := rows.Scan(&h.ID, &h.Timestamp, &h.URL, &h.Domain, &title, &snippet, &bm25); err != nil {
+			return nil, err
+		}
+		// bm25 is lower for better matches.
+		h.Title, h.Snippet, h.Score = title.String, snippet.String, -bm25
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// SearchText returns the application events whose process name, executable path or commercial name
+// match a full-text query, best matches first.
+func (r *AppRepository) SearchText(query string, sinceTime, untilTime time.Time, limit int) ([]SearchHit, error) {
+	match, err := matchExpression(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Names weigh more than paths, whose directories match many queries.
+	q := `
+		SELECT e.id, e.start_time, e.process_name, e.exe_path, e.commercial_name,
+			snippet(app_events_fts, -1, ?, ?, '…', ?), bm25(app_events_fts, 3.0, 1.0, 3.0) AS score
+		FROM app_events_fts JOIN app_events e ON e.id = app_events_fts.rowid
+		WHERE app_events_fts MATCH ?`
+	args := []interface{}{HighlightStart, HighlightEnd, snippetTokens, match}
+	if !sinceTime.IsZero() {
+		q += " AND (e.end_time IS NULL OR e.end_time >= ?)"
+		args = append(args, sinceTime.Unix())
+	}
+	if !untilTime.IsZero() {
+		q += " AND e.start_time <= ?"
+		args = append(args, untilTime.Unix())
+	}
+	q += " ORDER BY score, e.start_time DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	hits := []SearchHit{}
+	for rows.Next() {
+		h := SearchHit{Kind: HitKindApp}
+		var exePath, commercialName, snippet sql.NullString
+		var bm25 float64
+		if err := rows.Scan(&h.ID, &h.Timestamp, &h.ProcessName, &exePath, &commercialName, &snippet, &bm25); err != nil {
+			return nil, err
+		}
+		h.ExePath, h.Title, h.Snippet, h.Score = exePath.String, commercialName.String, snippet.String, -bm25
+		if h.Title == "" {
+			h.Title = h.ProcessName
+		}
+		hits = append(hits, h)
+	}
+	return hits, rows.Err()
+}
+
+// MergeHits merges the hits of several searches, best first, and returns at most limit of them.
+// bm25 scores of different indexes aren't comparable, so each search's scores are first rescaled
+// to [0, 1] from its worst to its best hit; ties go to the most recent hit.
+func MergeHits(limit int, searches ...[]SearchHit) []SearchHit {
+	var merged []SearchHit
+	for _, hits := range searches {
+		if len(hits) == 0 {
+			continue
+		}
+		lo, hi := hits[0].Score, hits[0].Score
+		for _, h := range hits {
+			lo, hi = min(lo, h.Score), max(hi, h.Score)
+		}
+		for _, h := range hits {
+			if hi > lo {
+				h.Score = (h.Score - lo) / (hi - lo)
+			} else {
+				h.Score = 1
+			}
+			merged = append(merged, h)
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		if merged[i].Score != merged[j].Score {
+			return merged[i].Score > merged[j].Score
+		}
+		return merged[i].Timestamp > merged[j].Timestamp
+	})
+	return merged[:min(limit, len(merged))]
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"sort"
+	"time"
+	"veda-anchor-engine/src/internal/calendar"
+	"veda-anchor-engine/src/internal/data/rollup"
+)
+
+const (
+	// maxSeriesBuckets caps the length of a time series.
+	maxSeriesBuckets = 1000
+	// maxSeriesApps is the number of applications with their own series; the rest are summed up in Other.
+	maxSeriesApps = 10
+)
+
+// SeriesBucket is a bucket of a time series, [Start, End) in Unix seconds.
+type SeriesBucket struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+}
+
+// AppSeries holds the foreground seconds of one application per bucket.
+type AppSeries struct {
+	ExecutablePath string `json:"executablePath"`
+	ProcessName    string `json:"processName"`
+	// Name is the display name of the application. It is filled in by the API layer.
+	Name         string  `json:"name"`
+	TotalSeconds int64   `json:"totalSeconds"`
+	Seconds      []int64 `json:"seconds"`
+}
+
+// ScreenTimeSeries is the screen time of a range, bucketed by hour, day, week or month.
+// All slices are aligned with Buckets, and buckets without usage are zero.
+type ScreenTimeSeries struct {
+	Unit    string         `json:"unit"`
+	Buckets []SeriesBucket `json:"buckets"`
+	Total   []int64        `json:"total"`
+	// Apps holds the most used applications of the range, most used first.
+	Apps []AppSeries `json:"apps"`
+	// Other sums up the applications that aren't in Apps.
+	Other []int64 `json:"other"`
+}
+
+// GetScreenTimeSeries returns the screen time between since and until, bucketed by unit.
+// The first and last buckets are whole, so they may extend beyond the range.
+// Series are computed from the rollups: hourly buckets from rollup_hourly, longer ones from rollup_app_daily.
+func (r *AppRepository) GetScreenTimeSeries(unit string, sinceTime, untilTime time.Time) (*ScreenTimeSeries, error) {
+	cal := calendar.Current()
+	bounds, err := cal.Buckets(unit, sinceTime, untilTime, maxSeriesBuckets)
+	if err != nil {
+		return nil, err
+	}
+	n := len(bounds) - 1
+
+	series := &ScreenTimeSeries{
+		Unit:    unit,
+		Buckets: make([]SeriesBucket, n),
+		Total:   make([]int64, n),
+		Apps:    []AppSeries{},
+		Other:   make([]int64, n),
+	}
+	for i := range n {
+		series.Buckets[i] = SeriesBucket{Start: bounds[i].Unix(), End: bounds[i+1].Unix()}
+	}
+
+	// bucketOf returns the index of the bucket t falls into, or -1.
+	bucketOf := func(t time.Time) int {
+		i := sort.Search(n, func(i int) bool { return bounds[i+1].After(t) })
+		if i == n || t.Before(bounds[0]) {
+			return -1
+		}
+		return i
+	}
+
+	perApp := make(map[string][]int64)
+	add := func(exePath string, at time.Time, seconds int64) {
+		i := bucketOf(at)
+		if i < 0 {
+			return
+		}
+		s, ok := perApp[exePath]
+		if !ok {
+			s = make([]int64, n)
+			perApp[exePath] = s
+		}
+		s[i] += seconds
+		series.Total[i] += seconds
+	}
+
+	if unit == calendar.UnitHour {
+		rows, err := r.db.Query(`
+			SELECT hour_start, key, seconds FROM rollup_hourly
+			WHERE kind = ? AND seconds > 0 AND hour_start >= ? AND hour_start < ?`,
+			rollup.KindApp, bounds[0].Unix(), bounds[n].Unix())
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var hourStart, seconds int64
+			var exePath string
+			if err := rows.Scan(&hourStart, &exePath, &seconds); err != nil {
+				return nil, err
+			}
+			add(exePath, time.Unix(hourStart, 0), seconds)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	} else {
+		rows, err := r.db.Query(`
+			SELECT day, exe_path, foreground_seconds FROM rollup_app_daily
+			WHERE foreground_seconds > 0 AND day >= ? AND day < ?`,
+			rollup.DayKey(bounds[0]), rollup.DayKey(bounds[n]))
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rows.Close() }()
+		for rows.Next() {
+			var day, exePath string
+			var seconds int64
+			if err := rows.Scan(&day, &exePath, &seconds); err != nil {
+				return nil, err
+			}
+			start, err := rollup.DayStart(day)
+			if err != nil {
+				continue
+			}
+			add(exePath, start, seconds)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	for exePath, seconds := range perApp {
+		app := AppSeries{ExecutablePath: exePath, ProcessName: rollup.ProcessName(exePath), Seconds: seconds}
+		for _, s := range seconds {
+			app.TotalSeconds += s
+		}
+		series.Apps = append(series.Apps, app)
+	}
+	sort.Slice(series.Apps, func(i, j int) bool {
+		if series.Apps[i].TotalSeconds != series.Apps[j].TotalSeconds {
+			return series.Apps[i].TotalSeconds > series.Apps[j].TotalSeconds
+		}
+		return series.Apps[i].ExecutablePath < series.Apps[j].ExecutablePath
+	})
+	if len(series.Apps) > maxSeriesApps {
+		for _, app := range series.Apps[maxSeriesApps:] {
+			for i, s := range app.Seconds {
+				series.Other[i] += s
+			}
+		}
+		series.Apps = series.Apps[:maxSeriesApps]
+	}
+	return series, nil
+}
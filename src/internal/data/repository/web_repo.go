@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/rollup"
 	"veda-anchor-engine/src/internal/data/write"
 )
 
@@ -42,7 +43,14 @@ func NewWebRepository(db *sql.DB) *WebRepository {
 }
 
 // GetUsageRanking returns the most visited domains in a time range.
+// Long and open-ended ranges are answered from the daily rollups.
 func (r *WebRepository) GetUsageRanking(sinceTime, untilTime time.Time) ([]WebUsageItem, error) {
+	if useRollups(sinceTime, untilTime) {
+		q, args := dayRange("SELECT domain, SUM(visits) as count FROM rollup_web_daily WHERE 1=1", nil, sinceTime, untilTime)
+		q += " GROUP BY domain HAVING count > 0 ORDER BY count DESC LIMIT 10"
+		return r.queryUsageRanking(q, args)
+	}
+
 	q := `
 		SELECT domain, COUNT(*) as count
 		FROM web_events
@@ -60,7 +68,11 @@ func (r *WebRepository) GetUsageRanking(sinceTime, untilTime time.Time) ([]WebUs
 	}
 
 	q += " GROUP BY domain ORDER BY count DESC LIMIT 10"
+	return r.queryUsageRanking(q, args)
+}
 
+// queryUsageRanking runs a ranking query returning (domain, count) rows.
+func (r *WebRepository) queryUsageRanking(q string, args []interface{}) ([]WebUsageItem, error) {
 	rows, err := r.db.Query(q, args...)
 	if err != nil {
 		return nil, err
@@ -79,65 +91,86 @@ func (r *WebRepository) GetUsageRanking(sinceTime, untilTime time.Time) ([]WebUs
 }
 
 // GetLogs retrieves web logs within a given time range.
+// It returns the 100 most recent matches as rows of display strings.
 func (r *WebRepository) GetLogs(queryStr, since, until string) ([][]string, error) {
-	var sinceTime, untilTime time.Time
-	var err error
+	page, err := r.GetLogsPage(queryStr, since, until, PageRequest{})
+	if err != nil {
+		return nil, err
+	}
 
-	if since != "" {
-		sinceTime, err = ParseTime(since)
-		if err != nil {
-			return nil, err
-		}
+	// Returns: timestamp, domain, url
+	var entries [][]string
+	for _, e := range page.Entries {
+		timestampStr := time.Unix(e.Timestamp, 0).Format("2006-01-02 15:04:05")
+		entries = append(entries, []string{timestampStr, e.Domain, e.URL})
 	}
+	return entries, nil
+}
 
-	if until != "" {
-		untilTime, err = ParseTime(until)
-		if err != nil {
-			return nil, err
-		}
+// GetLogsPage returns a page of the web logs matching a search, ordered by visit time.
+func (r *WebRepository) GetLogsPage(queryStr, since, until string, page PageRequest) (*WebLogPage, error) {
+	page, err := page.normalize()
+	if err != nil {
+		return nil, err
+	}
+	sinceTime, untilTime, err := ParseTimeRange(since, until)
+	if err != nil {
+		return nil, err
 	}
 
-	// Build the SQL query dynamically based on the provided time filters.
-	// Returns: timestamp, domain, url
-	q := "SELECT timestamp, domain, url FROM web_events WHERE 1=1"
+	// Build the filter dynamically based on the provided time filters.
+	where := "FROM web_events WHERE 1=1"
 	args := make([]interface{}, 0)
 
 	if queryStr != "" {
-		q += " AND domain LIKE ?"
+		where += " AND domain LIKE ?"
 		args = append(args, "%"+queryStr+"%")
 	}
 
 	if !sinceTime.IsZero() {
-		q += " AND timestamp >= ?"
+		where += " AND timestamp >= ?"
 		args = append(args, sinceTime.Unix())
 	}
 
 	if !untilTime.IsZero() {
-		q += " AND timestamp <= ?"
+		where += " AND timestamp <= ?"
 		args = append(args, untilTime.Unix())
 	}
 
-	q += " ORDER BY timestamp DESC LIMIT 100"
+	total, estimate, err := countRows(r.db, where, args)
+	if err != nil {
+		return nil, err
+	}
 
+	q, args, err := page.keyset("SELECT id, timestamp, domain, url, title, source, browser "+where, args, "timestamp")
+	if err != nil {
+		return nil, err
+	}
 	rows, err := r.db.Query(q, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer func() { _ = rows.Close() }()
 
-	var entries [][]string
+	entries := []WebLogEntry{}
 	for rows.Next() {
-		var timestamp int64
-		var domain string
-		var url string
-		if err := rows.Scan(&timestamp, &domain, &url); err != nil {
-			continue
+		var e WebLogEntry
+		var title, source, browser sql.NullString
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.Domain, &e.URL, &title, &source, &browser); err != nil {
+			return nil, err
 		}
-		timestampStr := time.Unix(timestamp, 0).Format("2006-01-02 15:04:05")
-		entries = append(entries, []string{timestampStr, domain, url})
+		e.Title, e.Source, e.Browser = title.String, source.String, browser.String
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
 
-	return entries, nil
+	n, info := page.pageInfo(len(entries), func(i int) (int64, int64) {
+		return entries[i].Timestamp, entries[i].ID
+	})
+	info.Total, info.TotalIsEstimate = total, estimate
+	return &WebLogPage{Entries: entries[:n], PageInfo: info}, nil
 }
 
 // GetBlockedDetails enriches a list of domains with metadata.
@@ -190,9 +223,68 @@ func (r *WebRepository) SaveMetadata(domain, title, iconURL string) error {
 	return err
 }
 
-// LogWebEvent records a visit to a URL.
-func (r *WebRepository) LogWebEvent(urlStr string) {
+// LogWebEvent records a visit to a URL. title is the page title, if known.
+func (r *WebRepository) LogWebEvent(urlStr, title string) {
 	domain := ExtractDomain(urlStr)
-	write.EnqueueWrite("INSERT INTO web_events (url, domain, timestamp) VALUES (?, ?, ?)",
-		urlStr, domain, time.Now().Unix())
+	now := time.Now()
+	write.EnqueueWrite("INSERT INTO web_events (url, domain, title, timestamp) VALUES (?, ?, NULLIF(?, ''), ?)",
+		urlStr, domain, title, now.Unix())
+	rollup.AddWebVisit(domain, now)
+}
+
+// Block event outcomes reported by the extension.
+const (
+	OutcomeBlocked   = "blocked"
+	OutcomePassed    = "passed"
+	OutcomeAbandoned = "abandoned"
+)
+
+// LogFrictionEvent records the outcome of an intervention shown for a URL.
+// outcome is one of OutcomeBlocked, OutcomePassed or OutcomeAbandoned.
+func (r *WebRepository) LogFrictionEvent(urlStr, action, outcome string, timestamp int64) {
+	if timestamp == 0 {
+		timestamp = time.Now().Unix()
+	}
+	domain := ExtractDomain(urlStr)
+	write.EnqueueWrite("INSERT INTO block_events (kind, target, url, action, outcome, timestamp) VALUES ('web', ?, ?, ?, ?, ?)",
+		domain, urlStr, action, outcome, timestamp)
+}
+
+// GetFrictionStats returns, per domain and action, how often an interstitial was passed or abandoned.
+func (r *WebRepository) GetFrictionStats(sinceTime, untilTime time.Time) ([]FrictionStat, error) {
+	q := `
+		SELECT target, action,
+			SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END) as passed,
+			SUM(CASE WHEN outcome = ? THEN 1 ELSE 0 END) as abandoned
+		FROM block_events
+		WHERE kind = 'web'
+	`
+	args := []interface{}{OutcomePassed, OutcomeAbandoned}
+
+	if !sinceTime.IsZero() {
+		q += " AND timestamp >= ?"
+		args = append(args, sinceTime.Unix())
+	}
+	if !untilTime.IsZero() {
+		q += " AND timestamp <= ?"
+		args = append(args, untilTime.Unix())
+	}
+
+	q += " GROUP BY target, action ORDER BY passed + abandoned DESC"
+
+	rows, err := r.db.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []FrictionStat
+	for rows.Next() {
+		var item FrictionStat
+		if err := rows.Scan(&item.Domain, &item.Action, &item.Passed, &item.Abandoned); err != nil {
+			continue
+		}
+		results = append(results, item)
+	}
+	return results, nil
 }
@@ -0,0 +1,235 @@
+package retention
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// This file contains the background job that prunes old raw data.
+//
+// DESIGN DECISION:
+// Deletes are issued in small batches through write.EnqueueWrite instead of one large DELETE.
+// A single statement over months of data would hold the write lock for a long time and stall
+// the monitoring writes queued behind it; small batches interleave with normal traffic.
+
+const (
+	// pruneInterval is the interval between two pruning runs.
+	pruneInterval = 1 * time.Hour
+	// initialDelay gives the engine time to settle before the first run.
+	initialDelay = 1 * time.Minute
+	// followUpDelay is the delay before the size cap is checked after age-based pruning.
+	followUpDelay = 2 * time.Minute
+	// batchSize is the maximum number of rows deleted by a single statement.
+	batchSize = 500
+	// spoolReplayedDays is how long the IDs of replayed spool records are kept.
+	spoolReplayedDays = 7
+)
+
+// prunableTable describes a table with raw data and the column that holds its age.
+type prunableTable struct {
+	name string
+	// timeColumn is a Unix timestamp expression used to decide a row's age.
+	timeColumn string
+	// filter restricts pruning to rows that are safe to delete, e.g. closed sessions.
+	filter string
+	days   func(config.RetentionSettings) int
+}
+
+// tables lists the prunable tables in the order they are sacrificed when the size cap is exceeded:
+// bookkeeping and diagnostic logs first, then the highest-volume raw history.
+var tables = []prunableTable{
+	// spool_replayed only guards against replaying a journal twice, which can't happen after a few days.
+	{name: "spool_replayed", timeColumn: "replayed_at", days: func(config.RetentionSettings) int { return spoolReplayedDays }},
+	{name: "logs", timeColumn: "timestamp", days: func(r config.RetentionSettings) int { return r.LogsDays }},
+	{name: "web_events", timeColumn: "timestamp", days: func(r config.RetentionSettings) int { return r.WebEventsDays }},
+	{name: "screen_time", timeColumn: "end_time", filter: "is_open = 0", days: func(r config.RetentionSettings) int { return r.ScreenTimeDays }},
+	{name: "idle_periods", timeColumn: "end_time", filter: "is_open = 0", days: func(r config.RetentionSettings) int { return r.ScreenTimeDays }},
+	{name: "app_events", timeColumn: "start_time", filter: "end_time IS NOT NULL", days: func(r config.RetentionSettings) int { return r.AppEventsDays }},
+}
+
+var triggerCh = make(chan struct{}, 1)
+
+// TriggerPrune requests an immediate pruning run, e.g. after the retention settings changed.
+func TriggerPrune() {
+	select {
+	case triggerCh <- struct{}{}:
+	default:
+	}
+}
+
+// StartPruner starts a goroutine that periodically prunes data older than the configured retention.
+func StartPruner(db *sql.DB, appLogger logger.Logger) {
+	go func() {
+		timer := time.NewTimer(initialDelay)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+			case <-triggerCh:
+				timer.Stop()
+			}
+
+			followUp, err := Prune(db, appLogger)
+			if err != nil {
+				appLogger.Printf("[Retention] Pruning failed: %v", err)
+			}
+			if followUp {
+				timer.Reset(followUpDelay)
+			} else {
+				timer.Reset(pruneInterval)
+			}
+		}
+	}()
+}
+
+// Prune enqueues the deletion of all rows older than the configured retention,
+// then enforces the database size cap, and finally checkpoints the WAL.
+//
+// The size cap is only evaluated once age-based deletes have been applied, since the size
+// can't be measured while they are still queued. followUp reports that this is pending.
+func Prune(db *sql.DB, appLogger logger.Logger) (followUp bool, err error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return false, err
+	}
+	settings := cfg.Retention
+
+	enqueued := 0
+	for _, t := range tables {
+		days := t.days(settings)
+		if days <= 0 {
+			continue
+		}
+		cutoff := time.Now().AddDate(0, 0, -days).Unix()
+
+		count, err := countOlderThan(db, t, cutoff)
+		if err != nil {
+			return false, fmt.Errorf("count %s: %w", t.name, err)
+		}
+		if count == 0 {
+			continue
+		}
+
+		appLogger.Printf("[Retention] Pruning %d rows older than %d days from %s", count, days, t.name)
+		enqueueBatches(t, cutoff, count)
+		enqueued += count
+	}
+
+	if settings.MaxDatabaseMB > 0 {
+		if enqueued > 0 {
+			followUp = true
+		} else {
+			n, err := enforceSizeCap(db, appLogger, int64(settings.MaxDatabaseMB)*1024*1024)
+			if err != nil {
+				return false, fmt.Errorf("size cap: %w", err)
+			}
+			enqueued += n
+		}
+	}
+
+	if enqueued > 0 {
+		// Move the pruned pages back from the WAL into the main file and truncate the WAL.
+		write.EnqueueWrite("PRAGMA wal_checkpoint(TRUNCATE)")
+	}
+	return followUp, nil
+}
+
+// whereClause builds the age condition for a table, including its safety filter.
+func (t prunableTable) whereClause() string {
+	where := t.timeColumn + " < ?"
+	if t.filter != "" {
+		where += " AND " + t.filter
+	}
+	return where
+}
+
+// countOlderThan counts the rows that are older than cutoff.
+func countOlderThan(db *sql.DB, t prunableTable, cutoff int64) (int, error) {
+	var count int
+	err := db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", t.name, t.whereClause()), cutoff).Scan(&count)
+	return count, err
+}
+
+// enqueueBatches enqueues enough batch deletes to remove count rows older than cutoff, oldest first.
+func enqueueBatches(t prunableTable, cutoff int64, count int) {
+	q := fmt.Sprintf("DELETE FROM %s WHERE id IN (SELECT id FROM %s WHERE %s ORDER BY id LIMIT %d)",
+		t.name, t.name, t.whereClause(), batchSize)
+	for deleted := 0; deleted < count; deleted += batchSize {
+		write.EnqueueWrite(q, cutoff)
+	}
+}
+
+// enforceSizeCap prunes the oldest rows until the space used by the database is estimated to fit into maxBytes.
+// Free pages are not counted, since SQLite reuses them for new data.
+func enforceSizeCap(db *sql.DB, appLogger logger.Logger, maxBytes int64) (int, error) {
+	var pageCount, freeCount, pageSize int64
+	if err := db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, err
+	}
+	if err := db.QueryRow("PRAGMA freelist_count").Scan(&freeCount); err != nil {
+		return 0, err
+	}
+	if err := db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, err
+	}
+
+	excess := (pageCount-freeCount)*pageSize - maxBytes
+	if excess <= 0 {
+		return 0, nil
+	}
+	appLogger.Printf("[Retention] Database exceeds size cap by %d bytes", excess)
+
+	enqueued := 0
+	for _, t := range tables {
+		if excess <= 0 {
+			break
+		}
+
+		var rows, bytes int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM " + t.name).Scan(&rows); err != nil {
+			return enqueued, err
+		}
+		if err := db.QueryRow("SELECT COALESCE(SUM(pgsize), 0) FROM dbstat WHERE name = ?", t.name).Scan(&bytes); err != nil {
+			return enqueued, err
+		}
+		if rows == 0 || bytes == 0 {
+			continue
+		}
+
+		// Estimate how many of the oldest rows have to go, using the table's average row size.
+		avgRowBytes := max(bytes/rows, 1)
+		drop := min(rows, excess/avgRowBytes+1)
+
+		cutoff, err := nthOldestTime(db, t, drop)
+		if err != nil {
+			return enqueued, err
+		}
+
+		appLogger.Printf("[Retention] Pruning %d oldest rows from %s to respect the size cap", drop, t.name)
+		enqueueBatches(t, cutoff, int(drop))
+		enqueued += int(drop)
+		excess -= drop * avgRowBytes
+	}
+	return enqueued, nil
+}
+
+// nthOldestTime returns a cutoff that selects roughly the n oldest prunable rows of a table.
+func nthOldestTime(db *sql.DB, t prunableTable, n int64) (int64, error) {
+	q := fmt.Sprintf("SELECT %s FROM %s", t.timeColumn, t.name)
+	if t.filter != "" {
+		q += " WHERE " + t.filter
+	}
+	q += fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET ?", t.timeColumn)
+
+	var cutoff int64
+	err := db.QueryRow(q, n).Scan(&cutoff)
+	if err == sql.ErrNoRows {
+		// Fewer rows than requested: everything prunable goes.
+		return time.Now().Unix() + 1, nil
+	}
+	return cutoff, err
+}
@@ -0,0 +1,219 @@
+package rollup
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"veda-anchor-engine/src/internal/calendar"
+)
+
+// dailyKey identifies a row of rollup_app_daily or rollup_web_daily.
+type dailyKey struct {
+	day string
+	key string
+}
+
+// hourKey identifies a row of rollup_hourly.
+type hourKey struct {
+	hour int64
+	key  string
+}
+
+// Rebuild recomputes the rollups from the raw event tables.
+//
+// Only the period still covered by raw data is recomputed, measure by measure: rollup rows older
+// than the oldest raw event are kept as they are, since their raw events may already have been pruned.
+// Everything runs in one transaction so readers never see half-rebuilt rollups.
+func Rebuild(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := rebuild(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func rebuild(tx *sql.Tx) error {
+	if err := rebuildAppLaunches(tx); err != nil {
+		return fmt.Errorf("app launches: %w", err)
+	}
+	if err := rebuildForeground(tx); err != nil {
+		return fmt.Errorf("foreground time: %w", err)
+	}
+	if err := rebuildWebVisits(tx); err != nil {
+		return fmt.Errorf("web visits: %w", err)
+	}
+
+	// Drop rows that no longer count anything.
+	if _, err := tx.Exec("DELETE FROM rollup_app_daily WHERE launches = 0 AND foreground_seconds = 0"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM rollup_web_daily WHERE visits = 0"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("DELETE FROM rollup_hourly WHERE count = 0 AND seconds = 0")
+	return err
+}
+
+// oldestDay returns the start of the day containing the oldest value of a timestamp expression,
+// or false if the table is empty.
+func oldestDay(tx *sql.Tx, table, expr string) (time.Time, bool, error) {
+	var oldest sql.NullInt64
+	if err := tx.QueryRow(fmt.Sprintf("SELECT MIN(%s) FROM %s", expr, table)).Scan(&oldest); err != nil {
+		return time.Time{}, false, err
+	}
+	if !oldest.Valid {
+		return time.Time{}, false, nil
+	}
+	return calendar.Current().StartOfDay(time.Unix(oldest.Int64, 0)), true, nil
+}
+
+func rebuildAppLaunches(tx *sql.Tx) error {
+	from, ok, err := oldestDay(tx, "app_events", "start_time")
+	if err != nil || !ok {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE rollup_app_daily SET launches = 0 WHERE day >= ?", from.Format(DayLayout)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE rollup_hourly SET count = 0 WHERE kind = ? AND hour_start >= ?", KindApp, from.Unix()); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT process_name, exe_path, start_time FROM app_events WHERE exe_path IS NOT NULL AND exe_path != ''")
+	if err != nil {
+		return err
+	}
+	daily := make(map[dailyKey]int64)
+	names := make(map[string]string)
+	hourly := make(map[hourKey]int64)
+	for rows.Next() {
+		var name, exe string
+		var start int64
+		if err := rows.Scan(&name, &exe, &start); err != nil {
+			continue
+		}
+		at := time.Unix(start, 0)
+		daily[dailyKey{DayKey(at), exe}]++
+		names[exe] = name
+		hourly[hourKey{HourStart(at).Unix(), exe}]++
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for k, n := range daily {
+		if _, err := tx.Exec(upsertAppLaunches, k.day, k.key, names[k.key], n); err != nil {
+			return err
+		}
+	}
+	return writeHourly(tx, KindApp, hourly, nil)
+}
+
+func rebuildForeground(tx *sql.Tx) error {
+	from, ok, err := oldestDay(tx, "screen_time", "start_time")
+	if err != nil || !ok {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE rollup_app_daily SET foreground_seconds = 0 WHERE day >= ?", from.Format(DayLayout)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE rollup_hourly SET seconds = 0 WHERE kind = ? AND hour_start >= ?", KindApp, from.Unix()); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT executable_path, start_time, end_time FROM screen_time WHERE executable_path IS NOT NULL AND executable_path != ''")
+	if err != nil {
+		return err
+	}
+	daily := make(map[dailyKey]int64)
+	hourly := make(map[hourKey]int64)
+	for rows.Next() {
+		var exe string
+		var start, end int64
+		if err := rows.Scan(&exe, &start, &end); err != nil {
+			continue
+		}
+		SplitByHour(time.Unix(start, 0), time.Unix(end, 0), func(hour time.Time, seconds int64) {
+			daily[dailyKey{DayKey(hour), exe}] += seconds
+			hourly[hourKey{hour.Unix(), exe}] += seconds
+		})
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for k, seconds := range daily {
+		if _, err := tx.Exec(upsertAppSeconds, k.day, k.key, ProcessName(k.key), seconds); err != nil {
+			return err
+		}
+	}
+	return writeHourly(tx, KindApp, nil, hourly)
+}
+
+func rebuildWebVisits(tx *sql.Tx) error {
+	from, ok, err := oldestDay(tx, "web_events", "timestamp")
+	if err != nil || !ok {
+		return err
+	}
+
+	if _, err := tx.Exec("UPDATE rollup_web_daily SET visits = 0 WHERE day >= ?", from.Format(DayLayout)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE rollup_hourly SET count = 0 WHERE kind = ? AND hour_start >= ?", KindWeb, from.Unix()); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query("SELECT domain, timestamp FROM web_events")
+	if err != nil {
+		return err
+	}
+	daily := make(map[dailyKey]int64)
+	hourly := make(map[hourKey]int64)
+	for rows.Next() {
+		var domain string
+		var ts int64
+		if err := rows.Scan(&domain, &ts); err != nil {
+			continue
+		}
+		at := time.Unix(ts, 0)
+		daily[dailyKey{DayKey(at), domain}]++
+		hourly[hourKey{HourStart(at).Unix(), domain}]++
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for k, n := range daily {
+		if _, err := tx.Exec(upsertWebVisits, k.day, k.key, n); err != nil {
+			return err
+		}
+	}
+	return writeHourly(tx, KindWeb, hourly, nil)
+}
+
+// writeHourly adds the aggregated counts and seconds to rollup_hourly.
+func writeHourly(tx *sql.Tx, kind string, counts, seconds map[hourKey]int64) error {
+	for k, n := range counts {
+		weekday, hourOfDay := hourCell(time.Unix(k.hour, 0))
+		if _, err := tx.Exec(upsertHourly, k.hour, weekday, hourOfDay, kind, k.key, n, 0); err != nil {
+			return err
+		}
+	}
+	for k, s := range seconds {
+		weekday, hourOfDay := hourCell(time.Unix(k.hour, 0))
+		if _, err := tx.Exec(upsertHourly, k.hour, weekday, hourOfDay, kind, k.key, 0, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package rollup
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+	"veda-anchor-engine/src/internal/calendar"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// This package maintains pre-aggregated usage tables so statistics over long ranges don't
+// have to scan the raw event tables.
+//
+// DESIGN DECISION:
+// Rollups are updated incrementally through write.EnqueueWrite right next to the raw insert they
+// summarize, so both land in the same sequential write stream; foreground time is added in the same
+// transaction as the interval update it comes from. They are never pruned by the retention job:
+// once raw events are gone, the rollups are the only record of past usage.
+//
+// Days and hours follow the calendar settings (timezone and day start hour). Changing them only
+// affects new rollup rows until Rebuild recomputes the period still covered by raw data.
+//
+// Tables:
+//   - rollup_app_daily: launches and foreground seconds per day and executable.
+//   - rollup_web_daily: visits per day and domain.
+//   - rollup_hourly: launches/visits and foreground seconds per hour, for apps and domains. Its day_of_week
+//     is the weekday of the day the hour belongs to, so with a day start of 4, 1 AM on Saturday counts as Friday.
+
+// DayLayout is the format of the day column in the daily rollup tables.
+const DayLayout = calendar.DateLayout
+
+// Kinds stored in rollup_hourly.
+const (
+	KindApp = "app"
+	KindWeb = "web"
+)
+
+// Upserts shared by incremental maintenance and Rebuild.
+const (
+	upsertAppLaunches = `
+		INSERT INTO rollup_app_daily (day, exe_path, process_name, launches) VALUES (?, ?, ?, ?)
+		ON CONFLICT(day, exe_path) DO UPDATE SET launches = launches + excluded.launches, process_name = excluded.process_name
+	`
+	upsertAppSeconds = `
+		INSERT INTO rollup_app_daily (day, exe_path, process_name, foreground_seconds) VALUES (?, ?, ?, ?)
+		ON CONFLICT(day, exe_path) DO UPDATE SET foreground_seconds = foreground_seconds + excluded.foreground_seconds
+	`
+	upsertWebVisits = `
+		INSERT INTO rollup_web_daily (day, domain, visits) VALUES (?, ?, ?)
+		ON CONFLICT(day, domain) DO UPDATE SET visits = visits + excluded.visits
+	`
+	upsertHourly = `
+		INSERT INTO rollup_hourly (hour_start, day_of_week, hour_of_day, kind, key, count, seconds) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(hour_start, kind, key) DO UPDATE SET count = count + excluded.count, seconds = seconds + excluded.seconds
+	`
+)
+
+// DayKey returns the rollup day a point in time belongs to.
+func DayKey(t time.Time) string {
+	return calendar.Current().Date(t)
+}
+
+// DayStart returns the start of a rollup day.
+func DayStart(day string) (time.Time, error) {
+	return calendar.Current().ParseDate(day)
+}
+
+// HourStart truncates a point in time to the start of its hour.
+func HourStart(t time.Time) time.Time {
+	return calendar.Current().StartOfHour(t)
+}
+
+// hourCell returns the day_of_week and hour_of_day of an hourly rollup row.
+func hourCell(hour time.Time) (int, int) {
+	cal := calendar.Current()
+	return int(cal.Weekday(hour)), hour.In(cal.Location()).Hour()
+}
+
+// ProcessName derives the process name recorded for an executable path.
+func ProcessName(exePath string) string {
+	// Paths come from Windows, so handle backslashes regardless of the build platform.
+	return filepath.Base(strings.ReplaceAll(exePath, `\`, "/"))
+}
+
+// AddAppLaunch records one launch of an application.
+func AddAppLaunch(exePath, processName string, at time.Time) {
+	write.EnqueueWrite(upsertAppLaunches, DayKey(at), exePath, processName, 1)
+	addHourly(KindApp, exePath, at, 1, 0)
+}
+
+// AddWebVisit records one visit to a domain.
+func AddWebVisit(domain string, at time.Time) {
+	write.EnqueueWrite(upsertWebVisits, DayKey(at), domain, 1)
+	addHourly(KindWeb, domain, at, 1, 0)
+}
+
+// AddForeground records foreground time of an application between start and end, as part of tx: the
+// transaction that updates the foreground interval the time comes from.
+// The span is split at hour boundaries so each part is attributed to the right hour and day.
+func AddForeground(tx *write.Tx, exePath string, start, end time.Time) {
+	addForeground(tx, exePath, start, end, 1)
+}
+
+// RemoveForeground takes back foreground time added by AddForeground, e.g. when it turns out the machine
+// was suspended in between.
+func RemoveForeground(tx *write.Tx, exePath string, start, end time.Time) {
+	addForeground(tx, exePath, start, end, -1)
+}
+
+func addForeground(tx *write.Tx, exePath string, start, end time.Time, sign int64) {
+	processName := ProcessName(exePath)
+	SplitByHour(start, end, func(hour time.Time, seconds int64) {
+		weekday, hourOfDay := hourCell(hour)
+		tx.Exec(upsertAppSeconds, DayKey(hour), exePath, processName, sign*seconds)
+		tx.Exec(upsertHourly, hour.Unix(), weekday, hourOfDay, KindApp, exePath, 0, sign*seconds)
+	})
+}
+
+// addHourly adds to the counters of an hourly rollup row.
+func addHourly(kind, key string, at time.Time, count, seconds int64) {
+	hour := HourStart(at)
+	weekday, hourOfDay := hourCell(hour)
+	write.EnqueueWrite(upsertHourly, hour.Unix(), weekday, hourOfDay, kind, key, count, seconds)
+}
+
+// SplitByHour calls fn for every hour overlapped by [start, end) with the hour's start
+// and the number of whole seconds of the span that fall into it.
+func SplitByHour(start, end time.Time, fn func(hour time.Time, seconds int64)) {
+	// Work on whole seconds so truncation at hour boundaries doesn't lose time.
+	start, end = start.Truncate(time.Second), end.Truncate(time.Second)
+	for cur := start; cur.Before(end); {
+		hour := HourStart(cur)
+		next := hour.Add(time.Hour)
+		if next.After(end) {
+			next = end
+		}
+		if seconds := int64(next.Sub(cur) / time.Second); seconds > 0 {
+			fn(hour, seconds)
+		}
+		cur = next
+	}
+}
@@ -0,0 +1,351 @@
+package schema
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// This file contains the versioned schema migrations.
+//
+// DESIGN DECISION:
+// The schema version is tracked in SQLite's PRAGMA user_version. Each migration runs in its own
+// transaction together with the user_version bump, so a crash mid-migration leaves the database at
+// the previous version and the migration is simply retried on the next start.
+// Migrations are append-only: once released, a migration must never be edited or renumbered.
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer engine build.
+var ErrSchemaTooNew = errors.New("database schema is newer than this engine supports")
+
+// Migration is a single, numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *sql.Tx) error
+}
+
+// migrations is the ordered list of all schema migrations. Versions must be consecutive, starting at 1.
+var migrations = []Migration{
+	{Version: 1, Name: "baseline schema", Up: migrateBaseline},
+	{Version: 2, Name: "usage rollups", Up: migrateRollups},
+	{Version: 3, Name: "spool replay log", Up: migrateSpoolReplayed},
+	{Version: 4, Name: "web event sources", Up: migrateWebEventSources},
+	{Version: 5, Name: "full-text search", Up: migrateFullTextSearch},
+	{Version: 6, Name: "foreground intervals", Up: migrateForegroundIntervals},
+	{Version: 7, Name: "system gaps", Up: migrateSystemGaps},
+	{Version: 8, Name: "idle periods", Up: migrateIdlePeriods},
+	{Version: 9, Name: "window titles", Up: migrateWindowTitles},
+	{Version: 10, Name: "parent processes", Up: migrateParentProcesses},
+	{Version: 11, Name: "executables catalog", Up: migrateExecutables},
+}
+
+// migrateBaseline creates the original schema. Databases created before versioning was introduced
+// already have these tables, so every statement is idempotent.
+func migrateBaseline(tx *sql.Tx) error {
+	if _, err := tx.Exec(AppSchema); err != nil {
+		return err
+	}
+	return addColumnIfMissing(tx, "app_events", "process_instance_key", "TEXT")
+}
+
+// migrateRollups creates the pre-aggregated usage tables and backfills them from the existing history.
+// The backfill is frozen here rather than calling the rollup package, whose code follows the current
+// schema and calendar. At this version screen_time rows carry the time of their last update and the
+// duration back from there, and days and hours are local time, the calendar's default; a calendar
+// configured later rebuilds the rollups.
+func migrateRollups(tx *sql.Tx) error {
+	if _, err := tx.Exec(RollupSchema); err != nil {
+		return err
+	}
+	_, err := tx.Exec(rollupBackfill)
+	return err
+}
+
+// localHour returns the start of the local hour of a Unix timestamp expression, as a Unix timestamp.
+func localHour(expr string) string {
+	return "CAST(strftime('%s', strftime('%Y-%m-%d %H:00:00', " + expr + ", 'unixepoch', 'localtime'), 'utc') AS INTEGER)"
+}
+
+// localHourCell is the day_of_week and hour_of_day columns of a local hour start.
+const localHourCell = "CAST(strftime('%w', hour_start, 'unixepoch', 'localtime') AS INTEGER), CAST(strftime('%H', hour_start, 'unixepoch', 'localtime') AS INTEGER)"
+
+// rollupBackfill is the backfill of migrateRollups.
+var rollupBackfill = `
+	INSERT INTO rollup_app_daily (day, exe_path, process_name, launches)
+	SELECT date(start_time, 'unixepoch', 'localtime'), exe_path, MAX(process_name), COUNT(*) FROM app_events
+	WHERE exe_path IS NOT NULL AND exe_path != '' GROUP BY 1, 2;
+
+	INSERT INTO rollup_hourly (hour_start, day_of_week, hour_of_day, kind, key, count)
+	SELECT hour_start, ` + localHourCell + `, 'app', exe_path, COUNT(*)
+	FROM (SELECT ` + localHour("start_time") + ` AS hour_start, exe_path FROM app_events WHERE exe_path IS NOT NULL AND exe_path != '')
+	GROUP BY hour_start, exe_path;
+
+	INSERT INTO rollup_web_daily (day, domain, visits)
+	SELECT date(timestamp, 'unixepoch', 'localtime'), domain, COUNT(*) FROM web_events WHERE domain IS NOT NULL GROUP BY 1, 2;
+
+	INSERT INTO rollup_hourly (hour_start, day_of_week, hour_of_day, kind, key, count)
+	SELECT hour_start, ` + localHourCell + `, 'web', domain, COUNT(*)
+	FROM (SELECT ` + localHour("timestamp") + ` AS hour_start, domain FROM web_events WHERE domain IS NOT NULL)
+	GROUP BY hour_start, domain;
+
+	-- Foreground time, split at hour boundaries.
+	CREATE TEMP TABLE rollup_backfill_foreground AS
+	WITH RECURSIVE pieces (exe_path, hour_start, start, end) AS (
+		SELECT executable_path, ` + localHour("timestamp - duration_seconds") + `, timestamp - duration_seconds, timestamp FROM screen_time
+		WHERE executable_path IS NOT NULL AND executable_path != '' AND duration_seconds > 0
+		UNION ALL
+		SELECT exe_path, hour_start + 3600, hour_start + 3600, end FROM pieces WHERE end > hour_start + 3600
+	)
+	SELECT exe_path, hour_start, MIN(end, hour_start + 3600) - start AS seconds FROM pieces;
+
+	-- The process name is the file name of the path.
+	INSERT INTO rollup_app_daily (day, exe_path, process_name, foreground_seconds)
+	SELECT date(hour_start, 'unixepoch', 'localtime'), exe_path,
+		replace(exe_path, rtrim(exe_path, replace(exe_path, '\', '')), ''), SUM(seconds)
+	FROM rollup_backfill_foreground WHERE seconds > 0 GROUP BY 1, 2
+	ON CONFLICT (day, exe_path) DO UPDATE SET foreground_seconds = excluded.foreground_seconds;
+
+	INSERT INTO rollup_hourly (hour_start, day_of_week, hour_of_day, kind, key, seconds)
+	SELECT hour_start, ` + localHourCell + `, 'app', exe_path, SUM(seconds)
+	FROM rollup_backfill_foreground WHERE seconds > 0 GROUP BY hour_start, exe_path
+	ON CONFLICT (hour_start, kind, key) DO UPDATE SET seconds = excluded.seconds;
+
+	DROP TABLE rollup_backfill_foreground;
+`
+
+// migrateSpoolReplayed creates the table that records which spooled writes were already replayed.
+func migrateSpoolReplayed(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS spool_replayed (
+			id TEXT PRIMARY KEY,
+			replayed_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_spool_replayed_replayed_at ON spool_replayed (replayed_at);
+	`)
+	return err
+}
+
+// migrateWebEventSources records where a web event comes from. Events logged by the extension
+// have no source; imported ones carry the import source and the browser they were imported from.
+func migrateWebEventSources(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "web_events", "source", "TEXT"); err != nil {
+		return err
+	}
+	return addColumnIfMissing(tx, "web_events", "browser", "TEXT")
+}
+
+// migrateFullTextSearch adds page titles and commercial names to the event tables and indexes
+// the existing events for full-text search.
+func migrateFullTextSearch(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "web_events", "title", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(tx, "app_events", "commercial_name", "TEXT"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(SearchSchema); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO web_events_fts (web_events_fts) VALUES ('rebuild')"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("INSERT INTO app_events_fts (app_events_fts) VALUES ('rebuild')")
+	return err
+}
+
+// migrateForegroundIntervals turns screen_time into foreground intervals. A legacy row was extended
+// in place and stamped with the time of its last update, so it becomes the closed interval
+// [timestamp - duration_seconds, timestamp]. SQLite can't change columns in place, so the table is rebuilt.
+func migrateForegroundIntervals(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE TABLE screen_time_intervals (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			executable_path TEXT,
+			pid INTEGER,
+			process_instance_key TEXT,
+			start_time INTEGER NOT NULL,
+			end_time INTEGER NOT NULL,
+			is_open INTEGER NOT NULL DEFAULT 0
+		);
+		INSERT INTO screen_time_intervals (id, executable_path, pid, start_time, end_time)
+		SELECT id, executable_path, pid, timestamp - MAX(COALESCE(duration_seconds, 0), 0), timestamp FROM screen_time;
+		DROP TABLE screen_time;
+		ALTER TABLE screen_time_intervals RENAME TO screen_time;
+	`); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ForegroundIndexes)
+	return err
+}
+
+// migrateSystemGaps creates the table of periods the engine couldn't observe.
+func migrateSystemGaps(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		-- system_gaps stores periods without data: the machine was suspended, or the wall clock jumped.
+		-- kind is 'suspend' or 'clock_jump'. end_time is before start_time when the clock jumped backwards.
+		CREATE TABLE IF NOT EXISTS system_gaps (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			start_time INTEGER NOT NULL,
+			end_time INTEGER NOT NULL,
+			kind TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_system_gaps_start_time ON system_gaps (start_time);
+	`)
+	return err
+}
+
+// migrateIdlePeriods creates the table of periods the user was away from the computer.
+func migrateIdlePeriods(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		-- idle_periods stores the periods without keyboard or mouse input, which don't count as screen time.
+		-- Like a foreground interval, the current one is open (is_open = 1) with end_time its last confirmation.
+		CREATE TABLE IF NOT EXISTS idle_periods (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			start_time INTEGER NOT NULL,
+			end_time INTEGER NOT NULL,
+			is_open INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_idle_periods_start_time ON idle_periods (start_time);
+		CREATE INDEX IF NOT EXISTS idx_idle_periods_end_time ON idle_periods (end_time);
+	`)
+	return err
+}
+
+// migrateWindowTitles records the title of the foreground window with each foreground interval.
+// It is NULL unless window title capture is enabled.
+func migrateWindowTitles(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "screen_time", "window_title", "TEXT"); err != nil {
+		return err
+	}
+	_, err := tx.Exec("CREATE INDEX IF NOT EXISTS idx_screen_time_title ON screen_time (executable_path, window_title)")
+	return err
+}
+
+// migrateParentProcesses records the resolved parent of each application run. Older events stored
+// only the parent's PID, as "PID: 1234" in parent_process_name; it is moved to parent_pid.
+func migrateParentProcesses(tx *sql.Tx) error {
+	if err := addColumnIfMissing(tx, "app_events", "parent_pid", "INTEGER"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(tx, "app_events", "parent_exe_path", "TEXT"); err != nil {
+		return err
+	}
+	if err := addColumnIfMissing(tx, "app_events", "parent_instance_key", "TEXT"); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		UPDATE app_events SET parent_pid = CAST(substr(parent_process_name, 6) AS INTEGER), parent_process_name = NULL
+		WHERE parent_process_name LIKE 'PID: %';
+		CREATE INDEX IF NOT EXISTS idx_app_events_instance ON app_events (process_instance_key);
+	`)
+	return err
+}
+
+// migrateExecutables creates the catalog of the executables seen running.
+func migrateExecutables(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		-- executables stores the identity of each version of an executable file: a file is identified by
+		-- its path, size and modification time, so an updated executable gets a new row.
+		CREATE TABLE IF NOT EXISTS executables (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL COLLATE NOCASE,
+			size INTEGER NOT NULL,
+			mod_time INTEGER NOT NULL,
+			sha256 TEXT,
+			signer TEXT,
+			signature_valid INTEGER NOT NULL DEFAULT 0,
+			product_name TEXT,
+			file_version TEXT,
+			first_seen INTEGER NOT NULL,
+			last_seen INTEGER NOT NULL,
+			UNIQUE (path, size, mod_time)
+		);
+		CREATE INDEX IF NOT EXISTS idx_executables_last_seen ON executables (last_seen);
+		CREATE INDEX IF NOT EXISTS idx_executables_sha256 ON executables (sha256);
+	`)
+	return err
+}
+
+// Migrate brings the database schema up to LatestVersion.
+// If any migration is pending on a non-empty database, a copy of the database is written to backupDir first.
+// It refuses to touch a database whose version is newer than this build knows about.
+func Migrate(db *sql.DB, backupDir string) error {
+	current, err := CurrentVersion(db)
+	if err != nil {
+		return fmt.Errorf("could not read schema version: %w", err)
+	}
+
+	latest := LatestVersion()
+	if current > latest {
+		return fmt.Errorf("%w (database version %d, supported version %d)", ErrSchemaTooNew, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	populated, err := hasUserTables(db)
+	if err != nil {
+		return fmt.Errorf("could not inspect database: %w", err)
+	}
+	if populated && backupDir != "" {
+		if err := backupBeforeMigration(db, backupDir, current); err != nil {
+			return fmt.Errorf("could not back up database before migrating: %w", err)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		if err := applyMigration(db, m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+		log.Printf("Applied schema migration %d: %s", m.Version, m.Name)
+	}
+	return nil
+}
+
+// applyMigration runs a single migration and records its version in the same transaction.
+func applyMigration(db *sql.DB, m Migration) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Another process (e.g. the native messaging host) may have migrated in the meantime.
+	version, err := CurrentVersion(tx)
+	if err != nil {
+		return err
+	}
+	if version >= m.Version {
+		return nil
+	}
+
+	if err := m.Up(tx); err != nil {
+		return err
+	}
+	// PRAGMA does not accept bound parameters.
+	if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", m.Version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backupBeforeMigration writes a consistent copy of the database using VACUUM INTO.
+func backupBeforeMigration(db *sql.DB, backupDir string, fromVersion int) error {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("pre-migration-v%d-%s.db", fromVersion, time.Now().Format("20060102-150405"))
+	path := filepath.Join(backupDir, name)
+	if _, err := db.Exec("VACUUM INTO ?", path); err != nil {
+		return err
+	}
+	log.Printf("Backed up database to %s before migrating", path)
+	return nil
+}
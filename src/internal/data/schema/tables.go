@@ -1,5 +1,7 @@
 package schema
 
+// AppSchema is the baseline schema, applied by migration 1.
+// It must not be changed; schema changes go into a new migration instead.
 const AppSchema = `
 	-- app_events stores information about running processes.
 	CREATE TABLE IF NOT EXISTS app_events (
@@ -49,7 +51,8 @@ const AppSchema = `
 		timestamp INTEGER NOT NULL
 	);
 
-	-- screen_time stores foreground window usage time.
+	-- screen_time stores foreground window usage time. Migration 6 turns it into foreground intervals,
+	-- see ForegroundIndexes.
 	CREATE TABLE IF NOT EXISTS screen_time (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		executable_path TEXT,
@@ -62,4 +65,130 @@ const AppSchema = `
 	CREATE INDEX IF NOT EXISTS idx_screen_time_timestamp ON screen_time (timestamp);
 	CREATE INDEX IF NOT EXISTS idx_screen_time_exe ON screen_time (executable_path);
 	CREATE INDEX IF NOT EXISTS idx_screen_time_pid ON screen_time (pid);
+
+	-- block_events stores interventions and their outcome, e.g. a delay interstitial that was passed or abandoned.
+	CREATE TABLE IF NOT EXISTS block_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		target TEXT NOT NULL,
+		url TEXT,
+		action TEXT NOT NULL,
+		outcome TEXT NOT NULL,
+		timestamp INTEGER NOT NULL
+	);
+
+	-- Indexes for block_events queries.
+	CREATE INDEX IF NOT EXISTS idx_block_events_timestamp ON block_events (timestamp);
+	CREATE INDEX IF NOT EXISTS idx_block_events_target ON block_events (target);
+
+	-- access_requests stores unblock requests sent from the block page or the agent, and the admin's decision.
+	CREATE TABLE IF NOT EXISTS access_requests (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		kind TEXT NOT NULL,
+		target TEXT NOT NULL,
+		url TEXT,
+		reason TEXT,
+		status TEXT NOT NULL DEFAULT 'pending',
+		requested_at INTEGER NOT NULL,
+		decided_at INTEGER,
+		expires_at INTEGER
+	);
+
+	-- Indexes for access_requests queries.
+	CREATE INDEX IF NOT EXISTS idx_access_requests_status ON access_requests (status);
+	CREATE INDEX IF NOT EXISTS idx_access_requests_decided_at ON access_requests (decided_at);
+`
+
+// ForegroundIndexes indexes screen_time once it holds foreground intervals: one row per stretch of
+// time [start_time, end_time] an application spent in the foreground. While it is still in the
+// foreground, the row is open (is_open = 1) and end_time is the last time the agent confirmed it.
+const ForegroundIndexes = `
+	CREATE INDEX IF NOT EXISTS idx_screen_time_start_time ON screen_time (start_time);
+	CREATE INDEX IF NOT EXISTS idx_screen_time_end_time ON screen_time (end_time);
+	CREATE INDEX IF NOT EXISTS idx_screen_time_exe ON screen_time (executable_path);
+	CREATE INDEX IF NOT EXISTS idx_screen_time_instance ON screen_time (process_instance_key);
+	CREATE INDEX IF NOT EXISTS idx_screen_time_open ON screen_time (pid) WHERE is_open = 1;
+`
+
+// RollupSchema defines the pre-aggregated usage tables maintained by the rollup package.
+// Unlike the raw event tables they are never pruned.
+const RollupSchema = `
+	-- rollup_app_daily stores launches and foreground seconds per local day (YYYY-MM-DD) and executable.
+	CREATE TABLE IF NOT EXISTS rollup_app_daily (
+		day TEXT NOT NULL,
+		exe_path TEXT NOT NULL,
+		process_name TEXT NOT NULL,
+		launches INTEGER NOT NULL DEFAULT 0,
+		foreground_seconds INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (day, exe_path)
+	);
+
+	-- rollup_web_daily stores visits per local day and domain.
+	CREATE TABLE IF NOT EXISTS rollup_web_daily (
+		day TEXT NOT NULL,
+		domain TEXT NOT NULL,
+		visits INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (day, domain)
+	);
+
+	-- rollup_hourly stores launches/visits (count) and foreground seconds per local hour.
+	-- kind is 'app' (key = executable path) or 'web' (key = domain).
+	CREATE TABLE IF NOT EXISTS rollup_hourly (
+		hour_start INTEGER NOT NULL,
+		day_of_week INTEGER NOT NULL,
+		hour_of_day INTEGER NOT NULL,
+		kind TEXT NOT NULL,
+		key TEXT NOT NULL,
+		count INTEGER NOT NULL DEFAULT 0,
+		seconds INTEGER NOT NULL DEFAULT 0,
+		PRIMARY KEY (hour_start, kind, key)
+	);
+
+	-- Indexes for rollup queries.
+	CREATE INDEX IF NOT EXISTS idx_rollup_app_daily_process_name ON rollup_app_daily (process_name);
+	CREATE INDEX IF NOT EXISTS idx_rollup_hourly_kind_key ON rollup_hourly (kind, key);
+`
+
+// SearchSchema defines the full-text indexes over the event tables. They are external content tables:
+// they store only the index, and triggers keep them in sync with the rows of the event tables.
+const SearchSchema = `
+	-- web_events_fts indexes the URL and page title of visits.
+	CREATE VIRTUAL TABLE IF NOT EXISTS web_events_fts USING fts5(
+		url, title,
+		content = 'web_events', content_rowid = 'id',
+		tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS web_events_fts_insert AFTER INSERT ON web_events BEGIN
+		INSERT INTO web_events_fts (rowid, url, title) VALUES (new.id, new.url, new.title);
+	END;
+	CREATE TRIGGER IF NOT EXISTS web_events_fts_delete AFTER DELETE ON web_events BEGIN
+		INSERT INTO web_events_fts (web_events_fts, rowid, url, title) VALUES ('delete', old.id, old.url, old.title);
+	END;
+	CREATE TRIGGER IF NOT EXISTS web_events_fts_update AFTER UPDATE OF url, title ON web_events BEGIN
+		INSERT INTO web_events_fts (web_events_fts, rowid, url, title) VALUES ('delete', old.id, old.url, old.title);
+		INSERT INTO web_events_fts (rowid, url, title) VALUES (new.id, new.url, new.title);
+	END;
+
+	-- app_events_fts indexes the process name, executable path and commercial name of application runs.
+	CREATE VIRTUAL TABLE IF NOT EXISTS app_events_fts USING fts5(
+		process_name, exe_path, commercial_name,
+		content = 'app_events', content_rowid = 'id',
+		tokenize = 'unicode61 remove_diacritics 2', prefix = '2 3'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS app_events_fts_insert AFTER INSERT ON app_events BEGIN
+		INSERT INTO app_events_fts (rowid, process_name, exe_path, commercial_name)
+		VALUES (new.id, new.process_name, new.exe_path, new.commercial_name);
+	END;
+	CREATE TRIGGER IF NOT EXISTS app_events_fts_delete AFTER DELETE ON app_events BEGIN
+		INSERT INTO app_events_fts (app_events_fts, rowid, process_name, exe_path, commercial_name)
+		VALUES ('delete', old.id, old.process_name, old.exe_path, old.commercial_name);
+	END;
+	CREATE TRIGGER IF NOT EXISTS app_events_fts_update AFTER UPDATE OF process_name, exe_path, commercial_name ON app_events BEGIN
+		INSERT INTO app_events_fts (app_events_fts, rowid, process_name, exe_path, commercial_name)
+		VALUES ('delete', old.id, old.process_name, old.exe_path, old.commercial_name);
+		INSERT INTO app_events_fts (rowid, process_name, exe_path, commercial_name)
+		VALUES (new.id, new.process_name, new.exe_path, new.commercial_name);
+	END;
 `
@@ -2,16 +2,64 @@ package schema
 
 import (
 	"database/sql"
+	"fmt"
+	"strings"
 )
 
-// CreateSchema defines and executes the SQL statements to create the database schema.
-func CreateSchema(db *sql.DB) error {
-	if _, err := db.Exec(AppSchema); err != nil {
-		return err
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// CurrentVersion returns the schema version recorded in PRAGMA user_version.
+func CurrentVersion(q querier) (int, error) {
+	var version int
+	err := q.QueryRow("PRAGMA user_version").Scan(&version)
+	return version, err
+}
+
+// LatestVersion returns the schema version this build migrates to.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// columnExists reports whether a table has a column with the given name.
+func columnExists(q querier, table, column string) (bool, error) {
+	rows, err := q.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return false, err
 	}
+	defer func() { _ = rows.Close() }()
 
-	// Ensure process_instance_key column exists.
-	_, _ = db.Exec("ALTER TABLE app_events ADD COLUMN process_instance_key TEXT")
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(name, column) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
+// addColumnIfMissing adds a column unless it already exists, which keeps migrations
+// safe to run against databases that were patched by older ad-hoc ALTER TABLE statements.
+func addColumnIfMissing(tx *sql.Tx, table, column, definition string) error {
+	exists, err := columnExists(tx, table, column)
+	if err != nil || exists {
+		return err
+	}
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
 
-	return nil
+// hasUserTables reports whether the database contains any tables, i.e. whether it is not freshly created.
+func hasUserTables(q querier) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'").Scan(&count)
+	return count > 0, err
 }
@@ -0,0 +1,186 @@
+package spool
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+// This package journals database writes to disk while the database is unavailable, and replays
+// them once it is healthy again.
+//
+// DESIGN DECISION:
+// Each process appends to its own journal file, so processes never interleave records. A record is a
+// 4-byte little-endian payload length, a 4-byte CRC-32 of the payload and the JSON payload itself.
+// A crash mid-append leaves at most a truncated last record, which the reader detects and ignores.
+// Every record carries a random ID that is stored in spool_replayed in the same transaction as the
+// replayed statement, so a journal that is replayed twice (e.g. after a crash) doesn't duplicate data.
+
+const (
+	journalExt = ".journal"
+	// maxJournalBytes caps the size of one process's journal so a long outage can't fill the disk.
+	maxJournalBytes = 32 * 1024 * 1024
+	// maxRecordBytes bounds the length prefix accepted by the reader.
+	maxRecordBytes = 1024 * 1024
+	headerSize     = 8
+)
+
+// ErrJournalFull is returned when the journal has reached maxJournalBytes.
+var ErrJournalFull = errors.New("spool journal is full")
+
+// record is a single journaled write.
+type record struct {
+	ID    string        `json:"id"`
+	Time  int64         `json:"time"`
+	Query string        `json:"query"`
+	Args  []interface{} `json:"args"`
+}
+
+var (
+	// mu serializes appends, and the replayer's rotation of this process's own journal.
+	mu          sync.Mutex
+	journalPath string
+)
+
+// Install makes this process journal writes that can't reach the database.
+// name identifies the process in the journal file name, e.g. "engine" or "native-host".
+func Install(name string) error {
+	dir, err := config.GetSpoolDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	journalPath = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, os.Getpid(), journalExt))
+	mu.Unlock()
+
+	write.SetFallback(Append)
+	return nil
+}
+
+// Append journals a write request. The file is opened and synced for every record so that
+// other processes can pick the journal up at any time and nothing is lost on a crash.
+func Append(query string, args []interface{}) error {
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(record{ID: id, Time: time.Now().Unix(), Query: query, Args: args})
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if journalPath == "" {
+		return errors.New("spool is not installed")
+	}
+
+	f, err := os.OpenFile(journalPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	if info, err := f.Stat(); err == nil && info.Size()+int64(headerSize+len(payload)) > maxJournalBytes {
+		return ErrJournalFull
+	}
+
+	buf := make([]byte, headerSize, headerSize+len(payload))
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(payload))
+	buf = append(buf, payload...)
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// readJournal reads all intact records of a journal file in order.
+// corrupt counts records that failed their checksum or couldn't be decoded; a truncated tail counts as one.
+func readJournal(path string) (records []record, corrupt int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	r := bufio.NewReader(f)
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			if err == io.EOF {
+				return records, corrupt, nil
+			}
+			// Partial header: the writer crashed mid-append.
+			return records, corrupt + 1, nil
+		}
+
+		length := binary.LittleEndian.Uint32(header[0:4])
+		if length > maxRecordBytes {
+			// The length itself is garbage, so there is no way to find the next record.
+			return records, corrupt + 1, nil
+		}
+		payload := make([]byte, length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return records, corrupt + 1, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.LittleEndian.Uint32(header[4:8]) {
+			corrupt++
+			continue
+		}
+
+		rec, err := decodeRecord(payload)
+		if err != nil {
+			corrupt++
+			continue
+		}
+		records = append(records, rec)
+	}
+}
+
+// decodeRecord decodes a payload, keeping integer arguments as integers rather than float64.
+func decodeRecord(payload []byte) (record, error) {
+	var rec record
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	if err := dec.Decode(&rec); err != nil {
+		return rec, err
+	}
+	for i, arg := range rec.Args {
+		n, ok := arg.(json.Number)
+		if !ok {
+			continue
+		}
+		if v, err := n.Int64(); err == nil {
+			rec.Args[i] = v
+		} else if v, err := n.Float64(); err == nil {
+			rec.Args[i] = v
+		}
+	}
+	return rec, nil
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,219 @@
+package spool
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/config"
+	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/write"
+)
+
+const (
+	// replayInterval is the interval between two checks for journals to replay.
+	replayInterval = 1 * time.Minute
+	// replayBatchSize is the number of records replayed per transaction.
+	replayBatchSize = 200
+	// replayingExt marks a journal that has been taken over by a replayer.
+	replayingExt = ".replaying"
+)
+
+// Status describes the spool directory and the replays done by this process.
+type Status struct {
+	Files        int    `json:"files"`
+	Bytes        int64  `json:"bytes"`
+	LastReplayAt int64  `json:"lastReplayAt"`
+	Replayed     int64  `json:"replayed"`
+	Duplicates   int64  `json:"duplicates"`
+	Failed       int64  `json:"failed"`
+	Corrupt      int64  `json:"corrupt"`
+	LastError    string `json:"lastError"`
+}
+
+var (
+	statusMu sync.Mutex
+	status   Status
+	// replayMu prevents two replays from running at the same time in this process.
+	replayMu sync.Mutex
+)
+
+// GetStatus returns the current size of the spool and the replay counters.
+func GetStatus() (Status, error) {
+	statusMu.Lock()
+	s := status
+	statusMu.Unlock()
+
+	dir, err := config.GetSpoolDir()
+	if err != nil {
+		return s, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return s, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !isJournal(e.Name()) {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			s.Files++
+			s.Bytes += info.Size()
+		}
+	}
+	return s, nil
+}
+
+// StartReplayer starts a goroutine that replays spooled writes into db now and then periodically.
+func StartReplayer(db *sql.DB, appLogger logger.Logger) {
+	go func() {
+		for {
+			if err := Replay(db, appLogger); err != nil {
+				appLogger.Printf("[Spool] Replay failed: %v", err)
+			}
+			time.Sleep(replayInterval)
+		}
+	}()
+}
+
+// Replay imports all journals in the spool directory, oldest record first within each journal.
+// A journal is deleted once all its records are in the database.
+func Replay(db *sql.DB, appLogger logger.Logger) error {
+	replayMu.Lock()
+	defer replayMu.Unlock()
+
+	dir, err := config.GetSpoolDir()
+	if err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var replayErr error
+	for _, e := range entries {
+		if e.IsDir() || !isJournal(e.Name()) {
+			continue
+		}
+		path, err := takeOver(filepath.Join(dir, e.Name()))
+		if err != nil {
+			// Most likely the owning process is appending right now; try again next time.
+			continue
+		}
+		if err := replayFile(db, appLogger, path); err != nil {
+			replayErr = fmt.Errorf("%s: %w", filepath.Base(path), err)
+			break
+		}
+	}
+
+	statusMu.Lock()
+	status.LastReplayAt = time.Now().Unix()
+	status.LastError = ""
+	if replayErr != nil {
+		status.LastError = replayErr.Error()
+	}
+	statusMu.Unlock()
+	return replayErr
+}
+
+// isJournal reports whether a file name belongs to a journal, active or taken over.
+func isJournal(name string) bool {
+	return strings.HasSuffix(name, journalExt) || strings.HasSuffix(name, replayingExt)
+}
+
+// takeOver renames an active journal so its owner starts a new one, and returns the new path.
+// Journals already taken over by an earlier, interrupted replay are returned as they are.
+func takeOver(path string) (string, error) {
+	if strings.HasSuffix(path, replayingExt) {
+		return path, nil
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	target := strings.TrimSuffix(path, journalExt) + fmt.Sprintf("-%d%s", time.Now().UnixNano(), replayingExt)
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// replayFile replays a journal in transactions of replayBatchSize records and deletes it afterwards.
+func replayFile(db *sql.DB, appLogger logger.Logger, path string) error {
+	records, corrupt, err := readJournal(path)
+	if err != nil {
+		return err
+	}
+
+	var replayed, duplicates, failed int64
+	for start := 0; start < len(records); start += replayBatchSize {
+		end := min(start+replayBatchSize, len(records))
+		r, d, f, err := replayBatch(db, records[start:end])
+		if err != nil {
+			return err
+		}
+		replayed += r
+		duplicates += d
+		failed += f
+	}
+
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	appLogger.Printf("[Spool] Replayed %s: %d records, %d duplicates, %d failed, %d corrupt",
+		filepath.Base(path), replayed, duplicates, failed, corrupt)
+
+	statusMu.Lock()
+	status.Replayed += replayed
+	status.Duplicates += duplicates
+	status.Failed += failed
+	status.Corrupt += int64(corrupt)
+	statusMu.Unlock()
+	return nil
+}
+
+// replayBatch replays records in a single transaction, skipping those that were already replayed.
+// Records whose statement fails are still marked as replayed, since retrying them can't succeed.
+func replayBatch(db *sql.DB, records []record) (replayed, duplicates, failed int64, err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now().Unix()
+	for _, rec := range records {
+		res, err := tx.Exec("INSERT OR IGNORE INTO spool_replayed (id, replayed_at) VALUES (?, ?)", rec.ID, now)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			duplicates++
+			continue
+		}
+
+		if _, err := tx.Exec(rec.Query, rec.Args...); err != nil {
+			if write.IsUnavailable(err) {
+				// The database is still not usable; keep the journal for the next attempt.
+				return 0, 0, 0, err
+			}
+			failed++
+			continue
+		}
+		replayed++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, 0, err
+	}
+	return replayed, duplicates, failed, nil
+}
@@ -0,0 +1,72 @@
+package write
+
+import (
+	"errors"
+	"sync/atomic"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// FallbackFunc stores a write request somewhere else when the database can't take it.
+type FallbackFunc func(query string, args []interface{}) error
+
+var fallback atomic.Pointer[FallbackFunc]
+
+// SetFallback registers fn to receive fire-and-forget writes while the database is unavailable:
+// before a writer has been started (e.g. because the database could not be opened), and when a
+// statement fails because the database is locked, corrupt or can't be written to.
+func SetFallback(fn FallbackFunc) {
+	fallback.Store(&fn)
+}
+
+// divert hands a request over to the fallback. It reports false if there is none or it failed.
+func divert(query string, args []interface{}) bool {
+	fn := fallback.Load()
+	if fn == nil {
+		return false
+	}
+	if err := (*fn)(query, args); err != nil {
+		return false
+	}
+	spooled.Add(1)
+	return true
+}
+
+// divertRequest hands a queued request over to the fallback.
+func divertRequest(req WriteRequest) bool {
+	if req.Stmts != nil {
+		return divertTx(req.Stmts)
+	}
+	return divert(req.Query, req.Args)
+}
+
+// divertTx hands the statements of a transaction over to the fallback, in order. It reports false if
+// there is no fallback or it failed; the statements handed over before the failure stay with it.
+func divertTx(stmts []Statement) bool {
+	for _, stmt := range stmts {
+		if !divert(stmt.Query, stmt.Args) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsUnavailable reports whether err means the database itself can't be used right now, as opposed
+// to a problem with the statement.
+func IsUnavailable(err error) bool {
+	if errors.Is(err, ErrWriterNotRunning) {
+		return true
+	}
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	// Extended result codes carry the primary code in the low byte.
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_BUSY, sqlite3.SQLITE_LOCKED, sqlite3.SQLITE_READONLY, sqlite3.SQLITE_IOERR,
+		sqlite3.SQLITE_CORRUPT, sqlite3.SQLITE_FULL, sqlite3.SQLITE_CANTOPEN, sqlite3.SQLITE_NOTADB:
+		return true
+	}
+	return false
+}
@@ -1,20 +1,214 @@
 package write
 
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// queueSize is the number of write requests that can be buffered before callers are slowed down.
+	queueSize = 1024
+	// enqueueTimeout is how long EnqueueWrite waits for room in a full queue before dropping the request.
+	enqueueTimeout = 5 * time.Second
+)
+
+var (
+	// ErrQueueFull is returned when a request could not be queued because the writer is not keeping up.
+	ErrQueueFull = errors.New("database write queue is full")
+	// ErrWriterNotRunning is returned when waiting for a write that no writer will ever execute.
+	ErrWriterNotRunning = errors.New("database writer is not running")
+)
+
 // WriteRequest represents a request to write to the database.
 type WriteRequest struct {
 	Query string
 	Args  []interface{}
+	// Stmts, set instead of Query, are statements that are committed together or not at all.
+	Stmts []Statement
+	// done receives the outcome of the request. It is nil for fire-and-forget writes.
+	done chan Result
+	// resume, set on pause markers, holds the writer until it is closed.
+	resume chan struct{}
+}
+
+// Statement is a statement of a transaction.
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// Tx collects the statements of a transaction queued with EnqueueTx. The zero value is an empty
+// transaction.
+type Tx struct {
+	stmts []Statement
+}
+
+// Exec adds a statement to the transaction.
+func (t *Tx) Exec(query string, args ...interface{}) {
+	t.stmts = append(t.stmts, Statement{Query: query, Args: args})
+}
+
+// Result is the outcome of a write request.
+type Result struct {
+	RowsAffected int64
+	LastInsertID int64
+	Err          error
 }
 
 // writeCh is the internal channel for sequencing write requests.
-var writeCh = make(chan WriteRequest, 100)
+var writeCh = make(chan WriteRequest, queueSize)
+
+// Counters exposed through GetStats.
+var (
+	written atomic.Int64
+	failed  atomic.Int64
+	dropped atomic.Int64
+	spooled atomic.Int64
+	batches atomic.Int64
+)
 
-// EnqueueWrite sends a write request to the database writer channel.
+// EnqueueWrite sends a write request to the database writer channel without waiting for it to be executed.
+// While no writer is running, the request goes to the fallback registered with SetFallback, if any.
+//
+// If the queue is full, it waits up to enqueueTimeout for room and then drops the request, so a stalled
+// writer slows callers down instead of blocking them forever. Before the writer is started, requests are
+// only buffered while there is room.
 func EnqueueWrite(query string, args ...interface{}) {
-	writeCh <- WriteRequest{Query: query, Args: args}
+	if !running.Load() && divert(query, args) {
+		return
+	}
+	if err := enqueue(context.Background(), WriteRequest{Query: query, Args: args}); err != nil {
+		dropped.Add(1)
+		log.Printf("[ERROR] Dropped write request: %v", err)
+	}
+}
+
+// EnqueueTx queues the statements of a transaction without waiting for them to be executed. They are
+// committed together or not at all; otherwise, it behaves like EnqueueWrite. Statements that go to
+// the fallback are handed over in order.
+func EnqueueTx(t *Tx) {
+	if len(t.stmts) == 0 {
+		return
+	}
+	if !running.Load() && divertTx(t.stmts) {
+		return
+	}
+	if err := enqueue(context.Background(), WriteRequest{Stmts: t.stmts}); err != nil {
+		dropped.Add(1)
+		log.Printf("[ERROR] Dropped write transaction: %v", err)
+	}
+}
+
+// EnqueueWriteWait queues a write request and waits until the writer has executed it.
+// It is meant for callers that need the outcome, e.g. the ID of an inserted row.
+func EnqueueWriteWait(ctx context.Context, query string, args ...interface{}) Result {
+	req := WriteRequest{Query: query, Args: args, done: make(chan Result, 1)}
+	if err := enqueue(ctx, req); err != nil {
+		return Result{Err: err}
+	}
+	return wait(ctx, req.done)
+}
+
+// Flush waits until every request queued before the call has been committed.
+// The service calls it before closing the database so queued data isn't lost on shutdown.
+func Flush(ctx context.Context) error {
+	if !running.Load() {
+		if len(writeCh) > 0 {
+			return ErrWriterNotRunning
+		}
+		return nil
+	}
+
+	// Requests are executed in order, so once this marker is reached everything before it is done.
+	marker := WriteRequest{done: make(chan Result, 1)}
+	if err := enqueue(ctx, marker); err != nil {
+		return err
+	}
+	return wait(ctx, marker.done).Err
+}
+
+// Pause waits until every request queued before the call has been committed, then holds the writer
+// until the returned resume function is called. New requests keep queueing up in the meantime.
+// It is used to take or restore snapshots of the database without writes in flight.
+func Pause(ctx context.Context) (resume func(), err error) {
+	if !running.Load() {
+		return func() {}, nil
+	}
+
+	marker := WriteRequest{done: make(chan Result, 1), resume: make(chan struct{})}
+	var once sync.Once
+	resume = func() { once.Do(func() { close(marker.resume) }) }
+	if err := enqueue(ctx, marker); err != nil {
+		return nil, err
+	}
+	if res := wait(ctx, marker.done); res.Err != nil {
+		// The writer may still reach the marker later; make sure it doesn't stay paused.
+		resume()
+		return nil, res.Err
+	}
+	return resume, nil
+}
+
+// enqueue puts a request on the queue, applying backpressure when it is full.
+func enqueue(ctx context.Context, req WriteRequest) error {
+	select {
+	case writeCh <- req:
+		return nil
+	default:
+	}
+
+	if !running.Load() {
+		return ErrWriterNotRunning
+	}
+
+	timer := time.NewTimer(enqueueTimeout)
+	defer timer.Stop()
+	select {
+	case writeCh <- req:
+		return nil
+	case <-timer.C:
+		return ErrQueueFull
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// wait waits for the outcome of a queued request.
+func wait(ctx context.Context, done <-chan Result) Result {
+	select {
+	case res := <-done:
+		return res
+	case <-ctx.Done():
+		return Result{Err: ctx.Err()}
+	}
+}
+
+// Stats describes the state of the write queue.
+type Stats struct {
+	Running       bool  `json:"running"`
+	QueueDepth    int   `json:"queueDepth"`
+	QueueCapacity int   `json:"queueCapacity"`
+	Written       int64 `json:"written"`
+	Failed        int64 `json:"failed"`
+	Dropped       int64 `json:"dropped"`
+	Spooled       int64 `json:"spooled"`
+	Batches       int64 `json:"batches"`
 }
 
-// GetWriteChannel returns the internal write channel for the writer to consume.
-func GetWriteChannel() chan WriteRequest {
-	return writeCh
+// GetStats returns the current queue depth and the counters accumulated since startup.
+func GetStats() Stats {
+	return Stats{
+		Running:       running.Load(),
+		QueueDepth:    len(writeCh),
+		QueueCapacity: cap(writeCh),
+		Written:       written.Load(),
+		Failed:        failed.Load(),
+		Dropped:       dropped.Load(),
+		Spooled:       spooled.Load(),
+		Batches:       batches.Load(),
+	}
 }
@@ -3,17 +3,200 @@ package write
 import (
 	"database/sql"
 	"log"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
-// StartDatabaseWriter starts a goroutine that listens for write requests on the writeCh channel
-// and executes them sequentially against the database.
-func StartDatabaseWriter(db *sql.DB) {
-	writeCh := GetWriteChannel()
-	for req := range writeCh {
-		_, err := db.Exec(req.Query, req.Args...)
-		if err != nil {
-			// If we can't write to the DB, log the failure.
-			log.Printf("[ERROR] Failed to execute write request: %v", err)
+// This file contains the single database writer.
+//
+// DESIGN DECISION:
+// Queued statements are grouped into transactions of up to maxBatchSize statements, collected for at
+// most maxBatchDelay. SQLite pays a WAL sync per transaction, so one transaction per batch is far cheaper
+// than one per statement. If any statement of a batch fails, the batch is rolled back and its statements
+// are replayed one by one, so a single bad statement never takes the rest of the batch down with it.
+// The statements of a transaction queued with EnqueueTx stay together either way.
+
+const (
+	// maxBatchSize is the maximum number of statements committed in one transaction.
+	maxBatchSize = 200
+	// maxBatchDelay is how long the writer keeps collecting statements for a batch.
+	maxBatchDelay = 50 * time.Millisecond
+)
+
+// running is set once a writer has been started.
+var running atomic.Bool
+
+// Start starts the goroutine that executes queued write requests in batches against the database.
+// Only the first call starts a writer; later calls do nothing.
+func Start(db *sql.DB) {
+	if !running.CompareAndSwap(false, true) {
+		return
+	}
+	go func() {
+		for first := range writeCh {
+			executeBatch(db, collectBatch(first))
+		}
+	}()
+}
+
+// collectBatch gathers the requests following first until the batch is full, maxBatchDelay has
+// passed, or a caller is waiting for the outcome of a request.
+func collectBatch(first WriteRequest) []WriteRequest {
+	batch := []WriteRequest{first}
+	if first.done != nil {
+		return batch
+	}
+
+	timer := time.NewTimer(maxBatchDelay)
+	defer timer.Stop()
+	for len(batch) < maxBatchSize {
+		select {
+		case req := <-writeCh:
+			batch = append(batch, req)
+			if req.done != nil {
+				return batch
+			}
+		case <-timer.C:
+			return batch
+		}
+	}
+	return batch
+}
+
+// executeBatch executes a batch in order. Statements that can't run inside a transaction and
+// flush or pause markers split the batch into several transactions.
+func executeBatch(db *sql.DB, batch []WriteRequest) {
+	var pending []WriteRequest
+	for _, req := range batch {
+		switch {
+		case req.Query == "" && req.Stmts == nil:
+			// Flush or pause marker: everything before it has to be committed first.
+			commit(db, pending)
+			pending = nil
+			reply(req, Result{})
+			if req.resume != nil {
+				<-req.resume
+			}
+		case runsAlone(req.Query):
+			commit(db, pending)
+			pending = nil
+			execOne(db, req)
+		default:
+			pending = append(pending, req)
+		}
+	}
+	commit(db, pending)
+}
+
+// runsAlone reports whether a statement must be executed outside of a transaction.
+func runsAlone(query string) bool {
+	q := strings.ToUpper(strings.TrimSpace(query))
+	// wal_checkpoint is a no-op inside a transaction, and VACUUM fails there.
+	return strings.HasPrefix(q, "PRAGMA") || strings.HasPrefix(q, "VACUUM")
+}
+
+// commit executes requests in a single transaction, falling back to one by one execution on failure.
+func commit(db *sql.DB, reqs []WriteRequest) {
+	if len(reqs) == 0 {
+		return
+	}
+	if len(reqs) == 1 {
+		execOne(db, reqs[0])
+		return
+	}
+
+	if results, err := execTx(db, reqs); err == nil {
+		batches.Add(1)
+		written.Add(int64(len(reqs)))
+		for i, req := range reqs {
+			reply(req, results[i])
 		}
+		return
+	}
+
+	for _, req := range reqs {
+		execOne(db, req)
+	}
+}
+
+// execTx executes requests in a transaction. If any of them fails, it rolls back and returns the error.
+func execTx(db *sql.DB, reqs []WriteRequest) ([]Result, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, len(reqs))
+	for i, req := range reqs {
+		if req.Stmts == nil {
+			res, err := tx.Exec(req.Query, req.Args...)
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+			results[i] = toResult(res)
+			continue
+		}
+		for _, stmt := range req.Stmts {
+			res, err := tx.Exec(stmt.Query, stmt.Args...)
+			if err != nil {
+				_ = tx.Rollback()
+				return nil, err
+			}
+			r := toResult(res)
+			results[i].RowsAffected += r.RowsAffected
+			results[i].LastInsertID = r.LastInsertID
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return results, nil
+}
+
+// execOne executes a single request on its own.
+func execOne(db *sql.DB, req WriteRequest) {
+	var res Result
+	var err error
+	if req.Stmts == nil {
+		var r sql.Result
+		if r, err = db.Exec(req.Query, req.Args...); err == nil {
+			res = toResult(r)
+		}
+	} else {
+		var results []Result
+		if results, err = execTx(db, []WriteRequest{req}); err == nil {
+			res = results[0]
+		}
+	}
+	if err != nil {
+		// Keep fire-and-forget writes for later if the database itself is the problem.
+		if req.done == nil && IsUnavailable(err) && divertRequest(req) {
+			return
+		}
+		// If we can't write to the DB, log the failure.
+		failed.Add(1)
+		log.Printf("[ERROR] Failed to execute write request: %v", err)
+		reply(req, Result{Err: err})
+		return
+	}
+	written.Add(1)
+	reply(req, res)
+}
+
+func toResult(res sql.Result) Result {
+	var r Result
+	r.RowsAffected, _ = res.RowsAffected()
+	r.LastInsertID, _ = res.LastInsertId()
+	return r
+}
+
+// reply delivers the outcome to a waiting caller, if any.
+func reply(req WriteRequest, res Result) {
+	if req.done != nil {
+		req.done <- res
 	}
 }
@@ -17,6 +17,14 @@ type Response struct {
 	ID     string      `json:"id"`
 	Result interface{} `json:"result,omitempty"`
 	Error  string      `json:"error,omitempty"`
+	// ErrorDetail carries machine-readable details of the error, e.g. the position of a syntax error.
+	ErrorDetail interface{} `json:"errorDetail,omitempty"`
+}
+
+// detailedError is implemented by errors that have details for the client.
+type detailedError interface {
+	error
+	ErrorDetail() interface{}
 }
 
 // GetIPCAddress returns the Windows Named Pipe address.
@@ -4,14 +4,17 @@ package ipc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
 	"time"
 
 	"veda-anchor-engine/src/api"
+	"veda-anchor-engine/src/internal/config"
 
 	"github.com/Microsoft/go-winio"
 )
@@ -46,6 +49,17 @@ func (s *Server) Start() error {
 	}
 }
 
+// IsServerRunning reports whether an engine is listening on the IPC pipe.
+func IsServerRunning() bool {
+	timeout := time.Second
+	conn, err := winio.DialPipe(GetIPCAddress(), &timeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
 func (s *Server) handleConnection(conn net.Conn) {
 	defer conn.Close()
 	decoder := json.NewDecoder(conn)
@@ -82,10 +96,87 @@ func (s *Server) dispatch(req Request) Response {
 		result, err = s.apiServer.GetAppLeaderboard(params.Since, params.Until)
 
 	case "GetScreenTime":
-		result, err = s.apiServer.GetScreenTime()
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetScreenTime(params.Since, params.Until)
+
+	case "GetAppGroupLeaderboard":
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetAppGroupLeaderboard(params.Since, params.Until)
+
+	case "GetAppGroupScreenTime":
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetAppGroupScreenTime(params.Since, params.Until)
+
+	case "GetAppGroup":
+		var params struct {
+			ExePath string `json:"exePath"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetAppGroup(params.ExePath)
+
+	case "GetScreenTimeSeries":
+		var params struct {
+			Unit  string `json:"unit"`
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetScreenTimeSeries(params.Unit, params.Since, params.Until)
+
+	case "GetUsageHeatmap":
+		var params struct {
+			Since  string `json:"since"`
+			Until  string `json:"until"`
+			App    string `json:"app"`
+			Domain string `json:"domain"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetUsageHeatmap(params.Since, params.Until, params.App, params.Domain)
+
+	case "GetSystemGaps":
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetSystemGaps(params.Since, params.Until)
+
+	case "GetAppTitleBreakdown":
+		var params struct {
+			ExePath string `json:"exePath"`
+			Since   string `json:"since"`
+			Until   string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetAppTitleBreakdown(params.ExePath, params.Since, params.Until)
+
+	case "GetIdleTime":
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetIdleTime(params.Since, params.Until)
 
 	case "GetTotalScreenTime":
-		result, err = s.apiServer.GetTotalScreenTime()
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetTotalScreenTime(params.Since, params.Until)
 
 	case "GetWebLeaderboard":
 		var params struct {
@@ -95,6 +186,17 @@ func (s *Server) dispatch(req Request) Response {
 		json.Unmarshal(req.Params, &params)
 		result, err = s.apiServer.GetWebLeaderboard(params.Since, params.Until)
 
+	case "GetFrictionStats":
+		var params struct {
+			Since string `json:"since"`
+			Until string `json:"until"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetFrictionStats(params.Since, params.Until)
+
+	case "RebuildRollups":
+		err = s.apiServer.RebuildRollups()
+
 	case "Search":
 		var params struct {
 			Query string `json:"query"`
@@ -113,6 +215,72 @@ func (s *Server) dispatch(req Request) Response {
 		json.Unmarshal(req.Params, &params)
 		result, err = s.apiServer.GetWebLogs(params.Query, params.Since, params.Until)
 
+	case "SearchPage":
+		var params struct {
+			Query  string `json:"query"`
+			Since  string `json:"since"`
+			Until  string `json:"until"`
+			Cursor string `json:"cursor"`
+			Limit  int    `json:"limit"`
+			Order  string `json:"order"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.SearchPage(params.Query, params.Since, params.Until, params.Cursor, params.Limit, params.Order)
+
+	case "GetProcessAncestry":
+		var params struct {
+			EventID int64 `json:"eventId"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetProcessAncestry(params.EventID)
+
+	case "GetExecutableInfo":
+		var params struct {
+			ExePath string `json:"exePath"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetExecutableInfo(params.ExePath)
+
+	case "ListExecutables":
+		var params struct {
+			Query  string `json:"query"`
+			Cursor string `json:"cursor"`
+			Limit  int    `json:"limit"`
+			Order  string `json:"order"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.ListExecutables(params.Query, params.Cursor, params.Limit, params.Order)
+
+	case "GetWebLogsPage":
+		var params struct {
+			Query  string `json:"query"`
+			Since  string `json:"since"`
+			Until  string `json:"until"`
+			Cursor string `json:"cursor"`
+			Limit  int    `json:"limit"`
+			Order  string `json:"order"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetWebLogsPage(params.Query, params.Since, params.Until, params.Cursor, params.Limit, params.Order)
+
+	case "SearchAll":
+		var params struct {
+			Query string `json:"query"`
+			Since string `json:"since"`
+			Until string `json:"until"`
+			Limit int    `json:"limit"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.SearchAll(params.Query, params.Since, params.Until, params.Limit)
+
+	case "FilterHistory":
+		var params struct {
+			Query string `json:"query"`
+			Limit int    `json:"limit"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.FilterHistory(params.Query, params.Limit)
+
 	// --- App Blocklist ---
 
 	case "GetAppBlocklist":
@@ -128,6 +296,30 @@ func (s *Server) dispatch(req Request) Response {
 		json.Unmarshal(req.Params, &names)
 		err = s.apiServer.UnblockApps(names)
 
+	case "GetAppRules":
+		result, err = s.apiServer.GetAppRules()
+
+	case "SetAppRule":
+		var params struct {
+			Name  string `json:"name"`
+			Scope string `json:"scope"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetAppRule(params.Name, params.Scope)
+
+	case "SetAppGroupRule":
+		var params struct {
+			GroupID string `json:"groupId"`
+			Scope   string `json:"scope"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetAppGroupRule(params.GroupID, params.Scope)
+
+	case "RemoveAppRule":
+		var name string
+		json.Unmarshal(req.Params, &name)
+		err = s.apiServer.RemoveAppRule(name)
+
 	case "ClearAppBlocklist":
 		err = s.apiServer.ClearAppBlocklist()
 
@@ -154,6 +346,23 @@ func (s *Server) dispatch(req Request) Response {
 		json.Unmarshal(req.Params, &domain)
 		err = s.apiServer.RemoveWebBlocklist(domain)
 
+	case "GetWebRules":
+		result, err = s.apiServer.GetWebRules()
+
+	case "SetWebRule":
+		var params struct {
+			Domain       string `json:"domain"`
+			Action       string `json:"action"`
+			DelaySeconds int    `json:"delaySeconds"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetWebRule(params.Domain, params.Action, params.DelaySeconds)
+
+	case "RemoveWebRule":
+		var domain string
+		json.Unmarshal(req.Params, &domain)
+		err = s.apiServer.RemoveWebRule(domain)
+
 	case "ClearWebBlocklist":
 		err = s.apiServer.ClearWebBlocklist()
 
@@ -165,6 +374,55 @@ func (s *Server) dispatch(req Request) Response {
 		json.Unmarshal(req.Params, &content)
 		err = s.apiServer.LoadWebBlocklist(content)
 
+	// --- Access Requests ---
+
+	case "RequestAccess":
+		var params struct {
+			Kind   string `json:"kind"`
+			Target string `json:"target"`
+			Reason string `json:"reason"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.RequestAccess(params.Kind, params.Target, params.Reason)
+
+	case "GetAccessRequests":
+		var params struct {
+			Status string `json:"status"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.GetAccessRequests(params.Status)
+
+	case "ApproveAccessRequest":
+		var params struct {
+			ID              int64  `json:"id"`
+			Password        string `json:"password"`
+			DurationMinutes int    `json:"durationMinutes"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.ApproveAccessRequest(params.ID, params.Password, params.DurationMinutes)
+
+	case "DenyAccessRequest":
+		var params struct {
+			ID       int64  `json:"id"`
+			Password string `json:"password"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.DenyAccessRequest(params.ID, params.Password)
+
+	case "WaitAccessDecisions":
+		var params struct {
+			Since          int64  `json:"since"`
+			AfterID        *int64 `json:"afterId"`
+			TimeoutSeconds int    `json:"timeoutSeconds"`
+		}
+		json.Unmarshal(req.Params, &params)
+		// Agents that don't send the ID of their last decision get the decisions after the whole second.
+		afterID := int64(math.MaxInt64)
+		if params.AfterID != nil {
+			afterID = *params.AfterID
+		}
+		result, err = s.apiServer.WaitAccessDecisions(params.Since, afterID, params.TimeoutSeconds)
+
 	// --- Auth ---
 
 	case "GetIsAuthenticated":
@@ -227,6 +485,139 @@ func (s *Server) dispatch(req Request) Response {
 		json.Unmarshal(req.Params, &params)
 		err = s.apiServer.ClearWebHistory(params.Password)
 
+	case "GetWriterStats":
+		result = s.apiServer.GetWriterStats()
+
+	case "GetSpoolStatus":
+		result, err = s.apiServer.GetSpoolStatus()
+
+	case "GetRetentionSettings":
+		result, err = s.apiServer.GetRetentionSettings()
+
+	case "SetRetentionSettings":
+		var params struct {
+			Password  string                   `json:"password"`
+			Retention config.RetentionSettings `json:"retention"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetRetentionSettings(params.Password, params.Retention)
+
+	case "GetIdleSettings":
+		result, err = s.apiServer.GetIdleSettings()
+
+	case "SetIdleSettings":
+		var params struct {
+			Password string              `json:"password"`
+			Idle     config.IdleSettings `json:"idle"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetIdleSettings(params.Password, params.Idle)
+
+	case "GetTitleSettings":
+		result, err = s.apiServer.GetTitleSettings()
+
+	case "SetTitleSettings":
+		var params struct {
+			Password string               `json:"password"`
+			Titles   config.TitleSettings `json:"titles"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetTitleSettings(params.Password, params.Titles)
+
+	case "GetAppGroupSettings":
+		result, err = s.apiServer.GetAppGroupSettings()
+
+	case "SetAppGroupSettings":
+		var params struct {
+			Password  string                  `json:"password"`
+			AppGroups config.AppGroupSettings `json:"appGroups"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetAppGroupSettings(params.Password, params.AppGroups)
+
+	case "GetCalendarSettings":
+		result, err = s.apiServer.GetCalendarSettings()
+
+	case "SetCalendarSettings":
+		var params struct {
+			Password string                  `json:"password"`
+			Calendar config.CalendarSettings `json:"calendar"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetCalendarSettings(params.Password, params.Calendar)
+
+	// --- Export & Import ---
+
+	case "GetExportDatasets":
+		result = s.apiServer.GetExportDatasets()
+
+	case "ExportToFile":
+		var params struct {
+			Dataset  string `json:"dataset"`
+			Format   string `json:"format"`
+			Since    string `json:"since"`
+			Until    string `json:"until"`
+			Path     string `json:"path"`
+			Password string `json:"password"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.ExportToFile(params.Dataset, params.Format, params.Since, params.Until, params.Path, params.Password)
+
+	case "ExportChunk":
+		var params struct {
+			Dataset string `json:"dataset"`
+			Format  string `json:"format"`
+			Since   string `json:"since"`
+			Until   string `json:"until"`
+			Cursor  int64  `json:"cursor"`
+			MaxRows int    `json:"maxRows"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.ExportChunk(params.Dataset, params.Format, params.Since, params.Until, params.Cursor, params.MaxRows)
+
+	case "StartHistoryImport":
+		var params struct {
+			Path    string `json:"path"`
+			Browser string `json:"browser"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.StartHistoryImport(params.Path, params.Browser)
+
+	case "GetHistoryImportStatus":
+		result = s.apiServer.GetHistoryImportStatus()
+
+	// --- Backup & Restore ---
+
+	case "CreateBackup":
+		var params struct {
+			Path     string `json:"path"`
+			Password string `json:"password"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.CreateBackup(params.Path, params.Password)
+
+	case "ListBackups":
+		result, err = s.apiServer.ListBackups()
+
+	case "RestoreBackup":
+		var params struct {
+			Path     string `json:"path"`
+			Password string `json:"password"`
+		}
+		json.Unmarshal(req.Params, &params)
+		result, err = s.apiServer.RestoreBackup(params.Path, params.Password)
+
+	case "GetBackupSettings":
+		result, err = s.apiServer.GetBackupSettings()
+
+	case "SetBackupSettings":
+		var params struct {
+			Password string                `json:"password"`
+			Backup   config.BackupSettings `json:"backup"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.SetBackupSettings(params.Password, params.Backup)
+
 	// --- Local Checks ---
 
 	case "CheckChromeExtension":
@@ -234,28 +625,55 @@ func (s *Server) dispatch(req Request) Response {
 
 	// --- Agent Communication ---
 
+	case "ReportForegroundStart":
+		var params struct {
+			PID                uint32 `json:"pid"`
+			ExePath            string `json:"exePath"`
+			ProcessInstanceKey string `json:"processInstanceKey"`
+			WindowTitle        string `json:"windowTitle"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.ReportForegroundStart(params.PID, params.ExePath, params.ProcessInstanceKey, params.WindowTitle)
+
+	case "ReportForegroundStop":
+		var params struct {
+			PID uint32 `json:"pid"`
+		}
+		json.Unmarshal(req.Params, &params)
+		err = s.apiServer.ReportForegroundStop(params.PID)
+
 	case "UpdateScreenTime":
+		// The seconds sent by older agents are ignored: the time since the previous report counts.
 		var params struct {
-			PID     uint32 `json:"pid"`
-			Seconds int64  `json:"seconds"`
+			PID         uint32 `json:"pid"`
+			Idle        bool   `json:"idle"`
+			IdleSeconds int64  `json:"idleSeconds"`
 		}
 		json.Unmarshal(req.Params, &params)
-		err = s.apiServer.UpdateScreenTime(params.PID, params.Seconds)
+		err = s.apiServer.UpdateScreenTime(params.PID, params.Idle, params.IdleSeconds)
 
 	case "ReportActiveApp":
 		var params struct {
-			PID     uint32 `json:"pid"`
-			exePath string `json:"exePath"`
+			PID         uint32 `json:"pid"`
+			ExePath     string `json:"exePath"`
+			WindowTitle string `json:"windowTitle"`
+			Idle        bool   `json:"idle"`
+			IdleSeconds int64  `json:"idleSeconds"`
 		}
 		json.Unmarshal(req.Params, &params)
-		err = s.apiServer.ReportActiveApp(params.PID, params.exePath)
+		err = s.apiServer.ReportActiveApp(params.PID, params.ExePath, params.WindowTitle, params.Idle, params.IdleSeconds)
 
 	default:
 		return Response{ID: req.ID, Error: "Unknown method: " + req.Method}
 	}
 
 	if err != nil {
-		return Response{ID: req.ID, Error: err.Error()}
+		resp := Response{ID: req.ID, Error: err.Error()}
+		var detailed detailedError
+		if errors.As(err, &detailed) {
+			resp.ErrorDetail = detailed.ErrorDetail()
+		}
+		return resp
 	}
 
 	return Response{ID: req.ID, Result: result}
@@ -3,22 +3,36 @@ package monitoring
 import (
 	"os"
 	"slices"
+	"sort"
 	"strings"
 
+	"veda-anchor-engine/src/internal/appgroup"
 	"veda-anchor-engine/src/internal/blocklist/app"
 	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/repository"
+	"veda-anchor-engine/src/internal/platform/app_filter"
+	"veda-anchor-engine/src/internal/platform/proc_sensing"
+	"veda-anchor-engine/src/internal/service/catalog"
 )
 
 // BlocklistSubscriber is a subscriber that enforces the application blocklist.
-// It checks each process against the blocklist and terminates blocked processes.
+// It checks each process against the app rules, by process name or application group, and terminates
+// blocked processes, and for rules that cover them, the processes they started, unless an approved
+// access request currently grants the app.
 type BlocklistSubscriber struct {
 	logger logger.Logger
+	apps   *repository.AppRepository
+	access *repository.AccessRepository
 }
 
 // NewBlocklistSubscriber creates a new BlocklistSubscriber with the given logger.
-func NewBlocklistSubscriber(appLogger logger.Logger) *BlocklistSubscriber {
+// appRepo provides the executables catalog that application groups are resolved from; accessRepo may
+// be nil, in which case access grants are not consulted.
+func NewBlocklistSubscriber(appLogger logger.Logger, appRepo *repository.AppRepository, accessRepo *repository.AccessRepository) *BlocklistSubscriber {
 	return &BlocklistSubscriber{
 		logger: appLogger,
+		apps:   appRepo,
+		access: accessRepo,
 	}
 }
 
@@ -27,34 +41,118 @@ func (s *BlocklistSubscriber) Name() string {
 	return "BlocklistSubscriber"
 }
 
-// OnProcessesChanged checks the process snapshot against the blocklist
+// blockTarget is a process to terminate and the name of the rule that covers it.
+// descendant is set if the process is covered as a process started by the blocked application.
+type blockTarget struct {
+	node       *ProcessNode
+	blockedBy  string
+	descendant bool
+}
+
+// OnProcessesChanged checks the process snapshot against the app rules
 // and kills any blocked processes.
 func (s *BlocklistSubscriber) OnProcessesChanged(snapshot ProcessSnapshot) {
-	blocklist, err := app.LoadAppBlocklist()
+	rules, err := app.LoadAppRules()
 	if err != nil {
 		s.logger.Printf("[BlocklistSubscriber] Failed to load blocklist: %v", err)
 		return
 	}
 
-	if len(blocklist) == 0 {
+	if len(rules) == 0 {
 		return
 	}
 
+	if s.access != nil {
+		granted, err := s.access.GetActiveGrants(repository.AccessKindApp)
+		if err != nil {
+			s.logger.Printf("[BlocklistSubscriber] Failed to load access grants: %v", err)
+		}
+		rules = slices.DeleteFunc(rules, func(rule app.AppRule) bool {
+			return slices.Contains(granted, rule.Name)
+		})
+	}
+
+	tree := snapshot.Tree
+	if tree == nil {
+		tree = NewProcessTree(snapshot.Processes)
+	}
+	targets := s.collectTargets(tree, snapshot, rules)
+
+	// Children go first, so a launcher can't restart a child that was just killed,
+	// and no process is left running orphaned.
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].node.Depth() > targets[j].node.Depth()
+	})
+	procs := make([]proc_sensing.ProcessInfo, len(targets))
+	for i, t := range targets {
+		procs[i] = t.node.Process
+	}
+	terminateProcesses(procs, func(i int, err error) {
+		t, proc := targets[i], procs[i]
+		if err != nil {
+			s.logger.Printf("[BlocklistSubscriber] Failed to kill process %s (pid %d): %v", proc.Name, proc.PID, err)
+		} else if !t.descendant {
+			s.logger.Printf("[BlocklistSubscriber] Killed blocked process %s (pid %d)", proc.Name, proc.PID)
+		} else {
+			s.logger.Printf("[BlocklistSubscriber] Killed process %s (pid %d) started by blocked %s", proc.Name, proc.PID, t.blockedBy)
+		}
+	})
+}
+
+// collectTargets returns the processes the rules terminate, each once.
+// Descendants that are system components or the engine itself are spared.
+func (s *BlocklistSubscriber) collectTargets(tree *ProcessTree, snapshot ProcessSnapshot, rules []app.AppRule) []blockTarget {
+	self := uint32(os.Getpid())
+	seen := make(map[uint32]bool)
+	var targets []blockTarget
+	add := func(n *ProcessNode, blockedBy string, descendant bool) {
+		if n.Process.PID == self || seen[n.Process.PID] {
+			return
+		}
+		seen[n.Process.PID] = true
+		targets = append(targets, blockTarget{node: n, blockedBy: blockedBy, descendant: descendant})
+	}
+
+	hasGroupRules := slices.ContainsFunc(rules, func(rule app.AppRule) bool { return rule.Group })
+	groups := appgroup.Current()
 	for _, proc := range snapshot.Processes {
-		procName := proc.Name
-		if procName == "" {
+		if proc.Name == "" {
+			continue
+		}
+		var groupIDs []string
+		if hasGroupRules && proc.ExePath != "" {
+			group, cataloged := groups.Lookup(s.apps, proc.ExePath)
+			groupIDs = append(groupIDs, group.ID)
+			if !cataloged {
+				// Until the catalog has inspected the executable, the groups of the executables cataloged
+				// at its path hold it too, so an application can't escape a rule by updating itself.
+				catalog.Prioritize(proc.ExePath)
+				groupIDs = append(groupIDs, groups.PathGroups(s.apps, proc.ExePath)...)
+			}
+		}
+		node := tree.Lookup(proc.PID)
+		if node == nil {
 			continue
 		}
 
-		if slices.Contains(blocklist, strings.ToLower(procName)) {
-			osProc, err := os.FindProcess(int(proc.PID))
-			if err == nil {
-				if err := osProc.Kill(); err != nil {
-					s.logger.Printf("[BlocklistSubscriber] Failed to kill process %s (pid %d): %v", procName, proc.PID, err)
-				} else {
-					s.logger.Printf("[BlocklistSubscriber] Killed blocked process %s (pid %d)", procName, proc.PID)
+		for _, rule := range rules {
+			if rule.Group && !slices.Contains(groupIDs, rule.Name) {
+				continue
+			}
+			if !rule.Group && rule.Name != strings.ToLower(proc.Name) {
+				continue
+			}
+			if rule.BlocksApp() {
+				add(node, rule.Name, false)
+			}
+			if rule.BlocksDescendants() {
+				for _, d := range node.Descendants() {
+					if !app_filter.ShouldExclude(d.Process.ExePath, &d.Process) {
+						add(d, rule.Name, true)
+					}
 				}
 			}
 		}
 	}
+	return targets
 }
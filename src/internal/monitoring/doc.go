@@ -4,13 +4,14 @@
 // The package provides:
 //   - MonitoringManager: Core component that orchestrates process monitoring with polling and recovery
 //   - ProcessSubscriber: Interface for components that want to receive process snapshots
-//   - Built-in subscribers: ProcessEventSubscriber, BlocklistSubscriber
+//   - Built-in subscribers: ProcessEventSubscriber, BlocklistSubscriber, GapSubscriber
+//   - ProcessTree: Parent/child structure of each snapshot, used to block the processes an application starts
 //
 // Usage:
 //
 //	manager := monitoring.NewMonitoringManager(logger, 2*time.Second)
 //	manager.RegisterSubscriber(monitoring.NewProcessEventSubscriber(logger, repo))
-//	manager.RegisterSubscriber(monitoring.NewBlocklistSubscriber(logger))
+//	manager.RegisterSubscriber(monitoring.NewBlocklistSubscriber(logger, repo, accessRepo))
 //	monitoring.SetGlobalManager(manager)
 //	manager.Start()
 //
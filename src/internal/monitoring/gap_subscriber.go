@@ -0,0 +1,44 @@
+package monitoring
+
+import (
+	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+// GapSubscriber is a subscriber that accounts for the gaps MonitoringManager detects between snapshots.
+// It records them in system_gaps, so statistics can show them as periods without data, and splits
+// the open foreground interval around them, so a machine that slept with an app in the foreground
+// doesn't credit that app with the night, and a clock set back doesn't credit it twice.
+type GapSubscriber struct {
+	logger logger.Logger
+	repo   *repository.AppRepository
+}
+
+// NewGapSubscriber creates a new GapSubscriber with the given logger and repository.
+func NewGapSubscriber(appLogger logger.Logger, appRepo *repository.AppRepository) *GapSubscriber {
+	return &GapSubscriber{
+		logger: appLogger,
+		repo:   appRepo,
+	}
+}
+
+// Name returns the subscriber name for logging purposes.
+func (s *GapSubscriber) Name() string {
+	return "GapSubscriber"
+}
+
+// OnProcessesChanged records the gap preceding the snapshot, if any.
+func (s *GapSubscriber) OnProcessesChanged(snapshot ProcessSnapshot) {
+	gap := snapshot.Gap
+	if gap == nil {
+		return
+	}
+	s.repo.RecordSystemGap(gap.LastSeen, gap.Resumed, gap.Kind)
+	if err := s.repo.SplitForeground(gap.LastSeen, gap.Resumed); err != nil {
+		s.logger.Printf("[GapSubscriber] Failed to split foreground interval: %v", err)
+	}
+	// The user wasn't idle in front of a sleeping machine either; the agent reports idle again after the resume.
+	if err := s.repo.EndIdle(gap.LastSeen); err != nil {
+		s.logger.Printf("[GapSubscriber] Failed to end idle period: %v", err)
+	}
+}
@@ -6,12 +6,17 @@ import (
 	"time"
 
 	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/repository"
 	"veda-anchor-engine/src/internal/platform/proc_sensing"
 )
 
 // DefaultPollingInterval is the default interval between process snapshots.
 const DefaultPollingInterval = 2 * time.Second
 
+// gapThreshold is how much longer than expected the time between two snapshots may be, on the wall
+// clock or the monotonic clock, or how far the two clocks may drift apart, before it counts as a gap.
+const gapThreshold = 30 * time.Second
+
 // MonitoringManager is the core component that orchestrates process monitoring.
 // It captures process snapshots at regular intervals and distributes them to registered subscribers.
 // It also handles self-healing through panic recovery and automatic restart.
@@ -28,6 +33,9 @@ type MonitoringManager struct {
 	restartDelay        time.Duration
 	restartMaxRetries   int
 	consecutiveFailures atomic.Int32
+	// lastSnapshotTime is the time of the previous snapshot, with its monotonic clock reading.
+	// It is only accessed by the event loop.
+	lastSnapshotTime time.Time
 }
 
 // globalManager is the singleton instance used for global reset operations.
@@ -173,17 +181,46 @@ func (m *MonitoringManager) captureAndNotify() {
 		return
 	}
 
-	m.lastTickTime.Store(time.Now())
+	now := time.Now()
+	m.lastTickTime.Store(now)
 	m.consecutiveFailures.Store(0)
 
 	snapshot := ProcessSnapshot{
 		Processes: procs,
-		Timestamp: time.Now(),
+		Timestamp: now,
+		Tree:      NewProcessTree(procs),
+		Gap:       m.detectGap(now),
 	}
+	m.lastSnapshotTime = now
 
 	m.notifySubscribers(snapshot)
 }
 
+// detectGap compares the time since the previous snapshot on the monotonic and the wall clock.
+// The monotonic clock can't jump; on Windows it keeps running while the machine sleeps, so a long
+// pause on both clocks is a suspend, and a disagreement between them is a change of the wall clock.
+func (m *MonitoringManager) detectGap(now time.Time) *Gap {
+	if m.lastSnapshotTime.IsZero() {
+		return nil
+	}
+	monotonic := now.Sub(m.lastSnapshotTime)
+	// Round(0) strips the monotonic clock reading, so Sub compares wall-clock times.
+	wall := now.Round(0).Sub(m.lastSnapshotTime.Round(0))
+
+	gap := &Gap{LastSeen: m.lastSnapshotTime.Round(0), Resumed: now.Round(0)}
+	switch {
+	case (wall - monotonic).Abs() > gapThreshold:
+		gap.Kind = repository.GapClockJump
+	case monotonic > m.pollingInterval+gapThreshold:
+		gap.Kind = repository.GapSuspend
+	default:
+		return nil
+	}
+	m.logger.Printf("[MonitoringManager] Detected %s: no observation between %s and %s",
+		gap.Kind, gap.LastSeen.Format(time.RFC3339), gap.Resumed.Format(time.RFC3339))
+	return gap
+}
+
 // notifySubscribers distributes a process snapshot to all registered subscribers.
 // Each subscriber is called in a protected wrapper that recovers from panics.
 func (m *MonitoringManager) notifySubscribers(snapshot ProcessSnapshot) {
@@ -1,7 +1,6 @@
 package monitoring
 
 import (
-	"fmt"
 	"strings"
 	"sync"
 	"time"
@@ -10,6 +9,8 @@ import (
 	"veda-anchor-engine/src/internal/data/repository"
 	"veda-anchor-engine/src/internal/platform/app_filter"
 	"veda-anchor-engine/src/internal/platform/proc_sensing"
+	"veda-anchor-engine/src/internal/service/appname"
+	"veda-anchor-engine/src/internal/service/catalog"
 )
 
 // ProcessEventSubscriber is a subscriber that tracks process start and end events.
@@ -45,19 +46,24 @@ func (s *ProcessEventSubscriber) OnProcessesChanged(snapshot ProcessSnapshot) {
 		currentKeys[p.UniqueKey()] = true
 	}
 
-	s.logEndedProcesses(currentKeys)
+	// Processes that ended during a gap were last seen before it.
+	endTime := snapshot.Timestamp
+	if snapshot.Gap != nil {
+		endTime = snapshot.Gap.LastSeen
+	}
+	s.logEndedProcesses(currentKeys, endTime)
 	s.logNewProcesses(snapshot.Processes)
 }
 
 // logEndedProcesses detects processes that have stopped since the last snapshot.
 // It closes their events in the database and updates internal state.
-func (s *ProcessEventSubscriber) logEndedProcesses(currentKeys map[string]bool) {
+func (s *ProcessEventSubscriber) logEndedProcesses(currentKeys map[string]bool, endTime time.Time) {
 	s.Lock()
 	defer s.Unlock()
 
 	for key, nameLower := range s.runningProcs {
 		if !currentKeys[key] {
-			s.repo.CloseAppEvent(key, time.Now().Unix())
+			s.repo.CloseAppEvent(key, endTime.Unix())
 
 			delete(s.runningProcs, key)
 			s.runningAppCounts[nameLower]--
@@ -107,14 +113,24 @@ func (s *ProcessEventSubscriber) logNewProcesses(procs []proc_sensing.ProcessInf
 
 		// Log the new process event to the database.
 		// Note: ShouldTrack is now handled by Agent - Engine logs all non-excluded processes
-		parentName := fmt.Sprintf("PID: %d", p.ParentPID)
-		s.repo.LogAppEvent(name, p.PID, parentName, exePath, time.Now().Unix(), key)
+		s.repo.LogAppEvent(name, p.PID, parentProcess(p, procs), exePath, time.Now().Unix(), key)
+		appname.Enqueue(key, exePath)
+		catalog.Enqueue(exePath)
 
 		s.runningProcs[key] = nameLower
 		s.runningAppCounts[nameLower]++
 	}
 }
 
+// parentProcess resolves the parent of a process from the snapshot it was seen in.
+func parentProcess(p proc_sensing.ProcessInfo, procs []proc_sensing.ProcessInfo) repository.ParentProcess {
+	parent := repository.ParentProcess{PID: p.ParentPID}
+	if found, ok := proc_sensing.FindParent(p, procs); ok {
+		parent.Name, parent.ExePath, parent.Key = found.Name, found.ExePath, found.UniqueKey()
+	}
+	return parent
+}
+
 // InitializeFromDatabase restores the in-memory state from active sessions in the database.
 // This is called on startup to recover the state after a restart.
 func (s *ProcessEventSubscriber) InitializeFromDatabase() {
@@ -0,0 +1,91 @@
+package monitoring
+
+import (
+	"veda-anchor-engine/src/internal/platform/proc_sensing"
+)
+
+// ProcessTree is the parent/child structure of the processes in a snapshot.
+//
+// A process's parent is the process with its ParentPID, provided it started no later than the child.
+// Windows doesn't clear the parent PID when the parent exits, so once the PID is recycled it refers to
+// an unrelated, younger process; such processes are treated as roots instead.
+type ProcessTree struct {
+	byPID map[uint32]*ProcessNode
+	roots []*ProcessNode
+}
+
+// ProcessNode is a process in a ProcessTree.
+type ProcessNode struct {
+	Process  proc_sensing.ProcessInfo
+	Parent   *ProcessNode
+	Children []*ProcessNode
+}
+
+// NewProcessTree builds the process tree of a snapshot.
+func NewProcessTree(procs []proc_sensing.ProcessInfo) *ProcessTree {
+	t := &ProcessTree{byPID: make(map[uint32]*ProcessNode, len(procs))}
+	nodes := make([]*ProcessNode, 0, len(procs))
+	for _, p := range procs {
+		n := &ProcessNode{Process: p}
+		t.byPID[p.PID] = n
+		nodes = append(nodes, n)
+	}
+
+	for _, n := range nodes {
+		parent := t.byPID[n.Process.ParentPID]
+		if parent != nil && isParent(parent, n) {
+			n.Parent = parent
+			parent.Children = append(parent.Children, n)
+		} else {
+			t.roots = append(t.roots, n)
+		}
+	}
+	return t
+}
+
+// isParent reports whether parent can be the parent of n. Besides the start times, it rules out links
+// that would close a cycle, which is only possible when start times are unknown.
+func isParent(parent, n *ProcessNode) bool {
+	if parent == n {
+		return false
+	}
+	if parent.Process.StartTimeNano != 0 && n.Process.StartTimeNano != 0 &&
+		parent.Process.StartTimeNano > n.Process.StartTimeNano {
+		return false
+	}
+	for a := parent.Parent; a != nil; a = a.Parent {
+		if a == n {
+			return false
+		}
+	}
+	return true
+}
+
+// Lookup returns the node of the process with a PID, or nil.
+func (t *ProcessTree) Lookup(pid uint32) *ProcessNode {
+	return t.byPID[pid]
+}
+
+// Roots returns the processes without a known parent.
+func (t *ProcessTree) Roots() []*ProcessNode {
+	return t.roots
+}
+
+// Descendants returns the processes started by n, directly or not, parents before their children.
+func (n *ProcessNode) Descendants() []*ProcessNode {
+	var descendants []*ProcessNode
+	for _, child := range n.Children {
+		descendants = append(descendants, child)
+		descendants = append(descendants, child.Descendants()...)
+	}
+	return descendants
+}
+
+// Depth returns the number of ancestors of n.
+func (n *ProcessNode) Depth() int {
+	depth := 0
+	for a := n.Parent; a != nil; a = a.Parent {
+		depth++
+	}
+	return depth
+}
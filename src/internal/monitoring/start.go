@@ -10,6 +10,7 @@ import (
 func StartDefault(
 	appLogger logger.Logger,
 	appRepo *repository.AppRepository,
+	accessRepo *repository.AccessRepository,
 	screenTimeStarter func(logger.Logger, *repository.AppRepository, *repository.WebRepository),
 ) *MonitoringManager {
 	manager := NewMonitoringManager(appLogger, DefaultPollingInterval)
@@ -18,9 +19,12 @@ func StartDefault(
 	processEventSubscriber.InitializeFromDatabase()
 	manager.RegisterSubscriber(processEventSubscriber)
 
-	blocklistSubscriber := NewBlocklistSubscriber(appLogger)
+	blocklistSubscriber := NewBlocklistSubscriber(appLogger, appRepo, accessRepo)
 	manager.RegisterSubscriber(blocklistSubscriber)
 
+	gapSubscriber := NewGapSubscriber(appLogger, appRepo)
+	manager.RegisterSubscriber(gapSubscriber)
+
 	SetGlobalManager(manager)
 	manager.Start()
 
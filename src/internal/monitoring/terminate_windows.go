@@ -0,0 +1,57 @@
+//go:build windows
+
+package monitoring
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows"
+
+	"veda-anchor-engine/src/internal/platform/proc_sensing"
+)
+
+// exitConfirmTimeout is how long terminated processes are given to exit.
+const exitConfirmTimeout = 2 * time.Second
+
+// terminateProcesses kills processes in order, without waiting for each one to exit, so a launcher
+// has no time to restart a child that was just killed. It then confirms in the background that they
+// exited, all within exitConfirmTimeout, and reports the outcome for each process to done, by index.
+func terminateProcesses(procs []proc_sensing.ProcessInfo, done func(i int, err error)) {
+	handles := make([]windows.Handle, len(procs))
+	for i, p := range procs {
+		h, err := windows.OpenProcess(windows.PROCESS_TERMINATE|windows.SYNCHRONIZE, false, p.PID)
+		if err != nil {
+			done(i, err)
+			continue
+		}
+		if err := windows.TerminateProcess(h, 1); err != nil {
+			_ = windows.CloseHandle(h)
+			done(i, err)
+			continue
+		}
+		handles[i] = h
+	}
+
+	go func() {
+		deadline := time.Now().Add(exitConfirmTimeout)
+		for i, h := range handles {
+			if h != 0 {
+				done(i, waitExit(h, time.Until(deadline)))
+				_ = windows.CloseHandle(h)
+			}
+		}
+	}()
+}
+
+// waitExit waits up to timeout for a process to exit.
+func waitExit(h windows.Handle, timeout time.Duration) error {
+	event, err := windows.WaitForSingleObject(h, uint32(max(timeout, 0).Milliseconds()))
+	if err != nil {
+		return fmt.Errorf("could not confirm exit: %w", err)
+	}
+	if event == uint32(windows.WAIT_TIMEOUT) {
+		return fmt.Errorf("still running %v after termination", exitConfirmTimeout)
+	}
+	return nil
+}
@@ -12,6 +12,22 @@ type ProcessSnapshot struct {
 	Processes []proc_sensing.ProcessInfo
 	// Timestamp is when this snapshot was captured.
 	Timestamp time.Time
+	// Tree is the process tree of Processes.
+	Tree *ProcessTree
+	// Gap is set when the system couldn't be observed since the previous snapshot.
+	Gap *Gap
+}
+
+// Gap is a period between two snapshots that the engine couldn't observe: the machine was suspended,
+// or the wall clock jumped.
+type Gap struct {
+	// LastSeen is the wall-clock time of the previous snapshot.
+	LastSeen time.Time
+	// Resumed is the wall-clock time of the snapshot after the gap. It is before LastSeen when the clock
+	// jumped backwards.
+	Resumed time.Time
+	// Kind is repository.GapSuspend or repository.GapClockJump.
+	Kind string
 }
 
 // ProcessSubscriber is the interface that subscribers must implement to receive process snapshots.
@@ -9,9 +9,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"unsafe"
 
 	"github.com/bi-zone/go-fileversion"
 	"go.mozilla.org/pkcs7"
+	"golang.org/x/sys/windows"
 )
 
 // GetPublisherName returns the organization name from the authenticode signature of the executable.
@@ -72,6 +74,43 @@ func GetPublisherName(filePath string) (string, error) {
 	return "", fmt.Errorf("no organization name found in any certificate")
 }
 
+// VerifySignature reports whether the executable has a valid authenticode signature that chains to a
+// trusted root, as checked by WinVerifyTrust. Revocation isn't checked, so the result doesn't depend on
+// network access. An unsigned file is not valid.
+func VerifySignature(filePath string) (bool, error) {
+	path, err := windows.UTF16PtrFromString(filePath)
+	if err != nil {
+		return false, err
+	}
+	data := &windows.WinTrustData{
+		Size:             uint32(unsafe.Sizeof(windows.WinTrustData{})),
+		UIChoice:         windows.WTD_UI_NONE,
+		RevocationChecks: windows.WTD_REVOKE_NONE,
+		UnionChoice:      windows.WTD_CHOICE_FILE,
+		StateAction:      windows.WTD_STATEACTION_VERIFY,
+		FileOrCatalogOrBlobOrSgnrOrCert: unsafe.Pointer(&windows.WinTrustFileInfo{
+			Size:     uint32(unsafe.Sizeof(windows.WinTrustFileInfo{})),
+			FilePath: path,
+		}),
+	}
+	verifyErr := windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+
+	// Release the state allocated by the verification.
+	data.StateAction = windows.WTD_STATEACTION_CLOSE
+	_ = windows.WinVerifyTrustEx(windows.InvalidHWND, &windows.WINTRUST_ACTION_GENERIC_VERIFY_V2, data)
+
+	return verifyErr == nil, nil
+}
+
+// GetFileVersion returns the file version from the version info resource.
+func GetFileVersion(exePath string) (string, error) {
+	info, err := fileversion.New(exePath)
+	if err != nil {
+		return "", err
+	}
+	return info.FileVersion(), nil
+}
+
 // GetProductName returns the product name from the version info resource.
 func GetProductName(exePath string) (string, error) {
 	info, err := fileversion.New(exePath)
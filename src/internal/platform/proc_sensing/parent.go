@@ -0,0 +1,30 @@
+package proc_sensing
+
+// FindParent returns the parent of a process. It is looked up in procs, usually the snapshot p comes
+// from, and then by PID. ok is false if the parent has exited, or if its PID now belongs to a process
+// started after p, which can't be its parent.
+func FindParent(p ProcessInfo, procs []ProcessInfo) (parent ProcessInfo, ok bool) {
+	if p.ParentPID == 0 || p.ParentPID == p.PID {
+		return ProcessInfo{}, false
+	}
+
+	found := false
+	for _, candidate := range procs {
+		if candidate.PID == p.ParentPID {
+			parent, found = candidate, true
+			break
+		}
+	}
+	if !found {
+		byPID, err := GetProcessByPID(p.ParentPID)
+		if err != nil {
+			return ProcessInfo{}, false
+		}
+		parent = byPID
+	}
+
+	if parent.StartTimeNano != 0 && p.StartTimeNano != 0 && parent.StartTimeNano > p.StartTimeNano {
+		return ProcessInfo{}, false
+	}
+	return parent, true
+}
@@ -0,0 +1,47 @@
+package appname
+
+import (
+	"veda-anchor-engine/src/internal/data/repository"
+	"veda-anchor-engine/src/internal/platform/executable"
+)
+
+// This package fills in the commercial names of logged application runs, which the full-text
+// search indexes along with the process name and path.
+//
+// DESIGN DECISION:
+// Reading the name means parsing the version info of the executable, which is too slow for the
+// monitoring loop, so runs are logged without it and a single background worker fills it in. Runs
+// that don't fit in the queue keep an empty name.
+
+// queueSize is the number of runs that can wait for their name.
+const queueSize = 256
+
+type run struct {
+	instanceKey string
+	exePath     string
+}
+
+var queue = make(chan run, queueSize)
+
+// Start starts the worker that fills in the names of queued runs.
+func Start(repo *repository.AppRepository) {
+	go func() {
+		for r := range queue {
+			if name, _ := executable.GetCommercialName(r.exePath); name != "" {
+				repo.SetCommercialName(r.instanceKey, name)
+			}
+		}
+	}()
+}
+
+// Enqueue queues a logged run for its commercial name. It never blocks; if the queue is full, the run
+// is dropped.
+func Enqueue(instanceKey, exePath string) {
+	if instanceKey == "" || exePath == "" {
+		return
+	}
+	select {
+	case queue <- run{instanceKey: instanceKey, exePath: exePath}:
+	default:
+	}
+}
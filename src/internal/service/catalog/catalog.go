@@ -0,0 +1,168 @@
+package catalog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"veda-anchor-engine/src/internal/appgroup"
+	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/repository"
+	"veda-anchor-engine/src/internal/platform/executable"
+)
+
+// This package maintains the catalog of executables: the hash, signer and version info of each
+// executable file seen running.
+//
+// DESIGN DECISION:
+// Inspecting a file means hashing it and verifying its signature, which takes a while for large
+// executables, so it happens on a single background worker fed by a queue instead of on the monitoring
+// loop. A file is identified by path, size and modification time: an unchanged file is only inspected
+// once, and an updated one is inspected again. Paths that don't fit in the queue are picked up from
+// app_events on the next start. Executables whose group an application rule is waiting for are queued
+// through Prioritize and inspected ahead of the others.
+
+const (
+	// queueSize is the number of paths that can wait for inspection.
+	queueSize = 256
+	// urgentSize is the number of prioritized paths that can wait for inspection.
+	urgentSize = 16
+	// prioritizeInterval is how long a prioritized path isn't queued again, so a file that can't be
+	// inspected doesn't keep the worker busy.
+	prioritizeInterval = time.Minute
+)
+
+var (
+	queue  = make(chan string, queueSize)
+	urgent = make(chan string, urgentSize)
+
+	prioritizedMu sync.Mutex
+	prioritized   = make(map[string]time.Time)
+)
+
+// Start starts the worker that catalogs queued executables. It first queues the executables of
+// logged application runs that aren't cataloged yet.
+func Start(repo *repository.AppRepository, l logger.Logger) {
+	go func() {
+		paths, err := repo.GetUncatalogedPaths()
+		if err != nil {
+			l.Printf("[Catalog] Failed to list uncataloged executables: %v", err)
+		}
+		for _, path := range paths {
+			Enqueue(path)
+		}
+	}()
+
+	go func() {
+		for {
+			var path string
+			select {
+			case path = <-urgent:
+			default:
+				select {
+				case path = <-urgent:
+				case path = <-queue:
+				}
+			}
+			if _, err := Catalog(repo, path); err != nil {
+				l.Printf("[Catalog] Failed to catalog %s: %v", path, err)
+			}
+		}
+	}()
+}
+
+// Enqueue queues an executable for cataloging. It never blocks; if the queue is full, the path is dropped.
+func Enqueue(exePath string) {
+	if exePath == "" {
+		return
+	}
+	select {
+	case queue <- exePath:
+	default:
+	}
+}
+
+// Prioritize queues an executable for cataloging ahead of the others. A path is queued at most once
+// per prioritizeInterval. It never blocks; if the queue is full, the path is dropped.
+func Prioritize(exePath string) {
+	if exePath == "" {
+		return
+	}
+	now := time.Now()
+
+	prioritizedMu.Lock()
+	defer prioritizedMu.Unlock()
+	for path, at := range prioritized {
+		if now.Sub(at) >= prioritizeInterval {
+			delete(prioritized, path)
+		}
+	}
+	if _, ok := prioritized[exePath]; ok {
+		return
+	}
+	select {
+	case urgent <- exePath:
+		prioritized[exePath] = now
+	default:
+	}
+}
+
+// Catalog records that an executable was seen now and returns its catalog entry. A version of the
+// file that isn't cataloged yet is inspected first; its entry is written through the write queue,
+// so the returned entry has no ID yet.
+func Catalog(repo *repository.AppRepository, exePath string) (*repository.Executable, error) {
+	stat, err := os.Stat(exePath)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", exePath)
+	}
+	size, modTime, now := stat.Size(), stat.ModTime().Unix(), time.Now()
+
+	existing, err := repo.FindExecutable(exePath, size, modTime)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		repo.TouchExecutable(exePath, size, modTime, now)
+		existing.LastSeen = max(existing.LastSeen, now.Unix())
+		return existing, nil
+	}
+
+	e, err := inspect(exePath)
+	if err != nil {
+		return nil, err
+	}
+	e.Size, e.ModTime = size, modTime
+	e.FirstSeen, e.LastSeen = now.Unix(), now.Unix()
+	repo.SaveExecutable(e)
+	appgroup.Cataloged(e)
+	return &e, nil
+}
+
+// inspect reads the identity of an executable file. Only the hash is required; the signature and
+// version info are left empty if the file has none.
+func inspect(exePath string) (repository.Executable, error) {
+	e := repository.Executable{Path: exePath}
+
+	f, err := os.Open(exePath)
+	if err != nil {
+		return e, err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return e, fmt.Errorf("could not hash file: %w", err)
+	}
+	e.SHA256 = hex.EncodeToString(h.Sum(nil))
+
+	e.Signer, _ = executable.GetPublisherName(exePath)
+	e.SignatureValid, _ = executable.VerifySignature(exePath)
+	e.ProductName, _ = executable.GetProductName(exePath)
+	e.FileVersion, _ = executable.GetFileVersion(exePath)
+	return e, nil
+}
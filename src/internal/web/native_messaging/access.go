@@ -0,0 +1,96 @@
+package native_messaging
+
+import (
+	"log"
+	"slices"
+	"time"
+	blocklist "veda-anchor-engine/src/internal/blocklist/web"
+	"veda-anchor-engine/src/internal/data/repository"
+)
+
+// decisionPollInterval is the interval at which access request decisions are checked.
+const decisionPollInterval = 2 * time.Second
+
+// loadWebRules loads the web rules and drops the ones lifted by an approved, unexpired access request.
+// access may be nil when the database is unavailable, in which case the rules are returned as-is.
+func loadWebRules(access *repository.AccessRepository) ([]blocklist.WebRule, error) {
+	rules, err := blocklist.LoadWebRules()
+	if err != nil || access == nil {
+		return rules, err
+	}
+
+	granted, err := access.GetActiveGrants(repository.AccessKindWeb)
+	if err != nil {
+		log.Printf("Failed to load access grants: %v", err)
+		return rules, nil
+	}
+	return slices.DeleteFunc(rules, func(r blocklist.WebRule) bool {
+		return slices.Contains(granted, r.Domain)
+	}), nil
+}
+
+// createAccessRequest stores an unblock request for the rule that covers the URL.
+// The request targets the rule's domain so that an approval lifts exactly that rule.
+func createAccessRequest(access *repository.AccessRepository, payload AccessRequestPayload) {
+	if access == nil {
+		log.Printf("Cannot create access request: database unavailable")
+		sendResponse(map[string]interface{}{
+			"type":    "access_request_error",
+			"payload": "database unavailable",
+		})
+		return
+	}
+
+	target := repository.ExtractDomain(payload.Url)
+	if rules, err := blocklist.LoadWebRules(); err == nil {
+		if rule, ok := blocklist.MatchRule(rules, target); ok {
+			target = rule.Domain
+		}
+	}
+
+	id, err := access.CreateRequest(repository.AccessKindWeb, target, payload.Url, payload.Reason)
+	if err != nil {
+		log.Printf("Error creating access request: %v", err)
+		sendResponse(map[string]interface{}{
+			"type":    "access_request_error",
+			"payload": err.Error(),
+		})
+		return
+	}
+
+	sendResponse(map[string]interface{}{
+		"type": "access_request_created",
+		"payload": map[string]interface{}{
+			"id":     id,
+			"target": target,
+			"status": repository.AccessStatusPending,
+		},
+	})
+}
+
+// pollAccessDecisions pushes the outcome of web access requests to the extension as they are decided.
+func pollAccessDecisions(access *repository.AccessRepository) {
+	// The cursor is the decision time and ID of the last decision seen.
+	lastDecidedAt, lastID := time.Now().Unix(), int64(0)
+	ticker := time.NewTicker(decisionPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		decisions, err := access.GetDecisionsSince(lastDecidedAt, lastID)
+		if err != nil {
+			log.Printf("Failed to get access decisions: %v", err)
+			continue
+		}
+
+		for _, d := range decisions {
+			lastDecidedAt, lastID = d.DecidedAt, d.ID
+			if d.Kind != repository.AccessKindWeb {
+				continue
+			}
+			sendResponse(map[string]interface{}{
+				"type":    "access_request_result",
+				"payload": d,
+			})
+		}
+	}
+}
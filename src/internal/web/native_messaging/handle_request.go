@@ -8,7 +8,8 @@ import (
 )
 
 // handleRequest dispatches the incoming request to the appropriate handler logic.
-func handleRequest(req Request, repo *repository.WebRepository) {
+// access may be nil when the database is unavailable.
+func handleRequest(req Request, repo *repository.WebRepository, access *repository.AccessRepository) {
 	log.Printf("Processing message type: %s", req.Type)
 
 	switch req.Type {
@@ -25,7 +26,7 @@ func handleRequest(req Request, repo *repository.WebRepository) {
 
 		log.Printf("Logging URL: %s", payload.Url)
 		// Write to DB via Repository (domain extracted automatically)
-		repo.LogWebEvent(payload.Url)
+		repo.LogWebEvent(payload.Url, payload.Title)
 
 	case "log_web_metadata":
 		var payload WebMetadataPayload
@@ -41,15 +42,63 @@ func handleRequest(req Request, repo *repository.WebRepository) {
 
 	case "get_web_blocklist":
 		// Send blocklist
-		bl, err := blocklist.LoadWebBlocklist()
+		bl := []string{} // Send empty list on error
+		rules, err := loadWebRules(access)
 		if err != nil {
 			log.Printf("Error loading blocklist: %v", err)
-			bl = []string{} // Send empty list on error
+		}
+		for _, rule := range rules {
+			if rule.Action == blocklist.ActionBlock {
+				bl = append(bl, rule.Domain)
+			}
 		}
 		sendResponse(map[string]interface{}{
 			"type":    "web_blocklist",
 			"payload": bl,
 		})
+	case "get_web_rules":
+		rules, err := loadWebRules(access)
+		if err != nil {
+			log.Printf("Error loading web rules: %v", err)
+			rules = []blocklist.WebRule{} // Send empty list on error
+		}
+		sendResponse(map[string]interface{}{
+			"type":    "web_rules",
+			"payload": rules,
+		})
+
+	case "get_web_action":
+		var payload WebActionPayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			log.Printf("Error unmarshalling get_web_action payload: %v", err)
+			return
+		}
+		sendResponse(map[string]interface{}{
+			"type":    "web_action",
+			"payload": resolveWebAction(payload.Url, access),
+		})
+
+	case "log_friction_event":
+		var payload FrictionEventPayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			log.Printf("Error unmarshalling log_friction_event payload: %v", err)
+			return
+		}
+		switch payload.Outcome {
+		case repository.OutcomeBlocked, repository.OutcomePassed, repository.OutcomeAbandoned:
+			repo.LogFrictionEvent(payload.Url, payload.Action, payload.Outcome, payload.Timestamp)
+		default:
+			log.Printf("Unknown friction outcome: %s", payload.Outcome)
+		}
+
+	case "request_access":
+		var payload AccessRequestPayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			log.Printf("Error unmarshalling request_access payload: %v", err)
+			return
+		}
+		createAccessRequest(access, payload)
+
 	case "add_to_web_blocklist":
 		var domain string
 		if err := json.Unmarshal(req.Payload, &domain); err != nil {
@@ -63,3 +112,20 @@ func handleRequest(req Request, repo *repository.WebRepository) {
 		log.Printf("Unknown message type: %s", req.Type)
 	}
 }
+
+// resolveWebAction decides what the extension should do when it navigates to a URL.
+func resolveWebAction(urlStr string, access *repository.AccessRepository) WebActionResult {
+	domain := repository.ExtractDomain(urlStr)
+	result := WebActionResult{Url: urlStr, Domain: domain, Action: string(blocklist.ActionAllow)}
+
+	rules, err := loadWebRules(access)
+	if err != nil {
+		log.Printf("Error loading web rules: %v", err)
+		return result
+	}
+	if rule, ok := blocklist.MatchRule(rules, domain); ok {
+		result.Action = string(rule.Action)
+		result.DelaySeconds = rule.DelaySeconds
+	}
+	return result
+}
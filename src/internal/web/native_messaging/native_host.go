@@ -1,6 +1,7 @@
 package native_messaging
 
 import (
+	"database/sql"
 	"encoding/binary"
 	"encoding/json"
 	"io"
@@ -8,13 +9,40 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/debug"
+	"sync"
 	"time"
 	"veda-anchor-engine/src/internal/config"
 	"veda-anchor-engine/src/internal/data"
 	"veda-anchor-engine/src/internal/data/repository"
-	"veda-anchor-engine/src/internal/data/write"
+	"veda-anchor-engine/src/internal/data/spool"
 )
 
+// dbRetryInterval is the interval between attempts to open an unavailable database.
+const dbRetryInterval = 30 * time.Second
+
+// repos holds the repositories of the database. They are nil until the database is available,
+// which may only happen after a retry.
+var repos struct {
+	sync.RWMutex
+	web    *repository.WebRepository
+	access *repository.AccessRepository
+}
+
+// setRepositories makes the repositories of an opened database available.
+func setRepositories(db *sql.DB) {
+	repos.Lock()
+	defer repos.Unlock()
+	repos.web = repository.NewWebRepository(db)
+	repos.access = repository.NewAccessRepository(db)
+}
+
+// currentRepositories returns the repositories, or nil while the database is unavailable.
+func currentRepositories() (*repository.WebRepository, *repository.AccessRepository) {
+	repos.RLock()
+	defer repos.RUnlock()
+	return repos.web, repos.access
+}
+
 // Run starts the native messaging host loop. It sets up logging, initializes the database,
 // and begins listening for messages from the browser extension via standard input.
 func Run() {
@@ -31,17 +59,27 @@ func Run() {
 		log.SetOutput(logFile)
 	}
 
+	// Journal writes to disk whenever the database can't take them; the engine replays them later
+	if err := spool.Install("native-host"); err != nil {
+		log.Printf("Failed to set up write spool: %v", err)
+	}
+
 	// Initialize Database (CRITICAL: Required for logging)
-	var repo *repository.WebRepository
 	db, err := data.InitDB()
 	if err != nil {
 		log.Printf("CRITICAL: Failed to initialize database: %v", err)
-		// We continue anyway, but DB writes will fail
+		// Web events are spooled until the database becomes available again
+		go retryInitDB()
 	} else {
 		log.Println("Database initialized successfully")
-		repo = repository.NewWebRepository(db)
-		go write.StartDatabaseWriter(db) // Sequential writes are still needed here
+		setRepositories(db)
 	}
+	// Commit whatever is still queued when Chrome disconnects.
+	defer func() {
+		if err := data.FlushWrites(); err != nil {
+			log.Printf("Failed to flush database writes: %v", err)
+		}
+	}()
 
 	// Catch panics to see why it crashes
 	defer func() {
@@ -62,6 +100,11 @@ func Run() {
 		pollWebBlocklist()
 	}()
 
+	// Push access request decisions to the extension
+	if _, access := currentRepositories(); access != nil {
+		go runDecisionPoller(access)
+	}
+
 	// Start continuous heartbeat updater
 	// This ensures the GUI knows we are alive even if no messages are flowing
 	go func() {
@@ -106,12 +149,41 @@ func Run() {
 			continue
 		}
 
-		handleRequest(req, repo)
+		repo, access := currentRepositories()
+		handleRequest(req, repo, access)
 
 		log.Println("Message processed successfully")
 	}
 }
 
+// retryInitDB keeps trying to open the database. Once it succeeds, InitDB starts the writer
+// and new events go to the database instead of the spool, and web logging, access requests and
+// the decision poller become available.
+func retryInitDB() {
+	for {
+		time.Sleep(dbRetryInterval)
+		db, err := data.InitDB()
+		if err != nil {
+			continue
+		}
+		log.Println("Database initialized successfully after retry")
+		setRepositories(db)
+		_, access := currentRepositories()
+		runDecisionPoller(access)
+		return
+	}
+}
+
+// runDecisionPoller pushes access request decisions to the extension until the host exits (panic safe).
+func runDecisionPoller(access *repository.AccessRepository) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("PANIC in Access Decision Poller: %v", r)
+		}
+	}()
+	pollAccessDecisions(access)
+}
+
 // Stop sends a stopping message to the extension to prevent it from reconnecting.
 func Stop() {
 	sendResponse(map[string]interface{}{
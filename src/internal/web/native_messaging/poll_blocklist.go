@@ -12,27 +12,42 @@ const (
 	pollInterval = 500 * time.Millisecond
 )
 
-// pollWebBlocklist periodically checks for changes in the web blocklist and sends updates to the extension.
+// pollWebBlocklist periodically checks for changes in the web rules and sends updates to the extension.
+// Both the legacy web_blocklist message (hard blocks only) and the full web_rules set are sent,
+// so extensions that don't understand friction actions keep working.
+// Rules lifted by an approved access request are left out until the approval expires.
 func pollWebBlocklist() {
-	var lastBlocklist []string
+	var lastRules []blocklist.WebRule
 	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		// Load blocklist directly from data package
-		list, err := blocklist.LoadWebBlocklist()
+		// Load rules directly from data package
+		_, access := currentRepositories()
+		rules, err := loadWebRules(access)
 		if err != nil {
-			log.Printf("Failed to get web blocklist: %v", err)
+			log.Printf("Failed to get web rules: %v", err)
 			continue
 		}
 
-		// Only send an update if the blocklist has changed.
-		if !reflect.DeepEqual(list, lastBlocklist) {
-			lastBlocklist = list
+		// Only send an update if the rules have changed.
+		if !reflect.DeepEqual(rules, lastRules) {
+			lastRules = rules
+
+			list := []string{}
+			for _, rule := range rules {
+				if rule.Action == blocklist.ActionBlock {
+					list = append(list, rule.Domain)
+				}
+			}
 			sendResponse(map[string]interface{}{
 				"type":    "web_blocklist",
 				"payload": list,
 			})
+			sendResponse(map[string]interface{}{
+				"type":    "web_rules",
+				"payload": rules,
+			})
 		}
 	}
 }
@@ -16,6 +16,34 @@ type WebMetadataPayload struct {
 	IconURL string `json:"iconUrl"`
 }
 
+// FrictionEventPayload is the payload for the log_friction_event message from the extension.
+// It is sent whenever an interstitial is passed or abandoned, or a block page is shown.
+type FrictionEventPayload struct {
+	Url       string `json:"url"`
+	Action    string `json:"action"`
+	Outcome   string `json:"outcome"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// WebActionPayload is the payload for the get_web_action message from the extension.
+type WebActionPayload struct {
+	Url string `json:"url"`
+}
+
+// WebActionResult tells the extension what to do with a URL.
+type WebActionResult struct {
+	Url          string `json:"url"`
+	Domain       string `json:"domain"`
+	Action       string `json:"action"`
+	DelaySeconds int    `json:"delaySeconds,omitempty"`
+}
+
+// AccessRequestPayload is the payload for the request_access message sent from the block page.
+type AccessRequestPayload struct {
+	Url    string `json:"url"`
+	Reason string `json:"reason"`
+}
+
 // Request is a message received from the browser extension.
 type Request struct {
 	Type    string          `json:"type"`
@@ -0,0 +1,99 @@
+package windowtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+	"veda-anchor-engine/src/internal/config"
+)
+
+// This package normalizes the window titles recorded with screen time.
+//
+// DESIGN DECISION:
+// Titles are normalized by the engine rather than the agent, so the rules apply the same way whatever
+// agent version reports them, and a title that is redacted never reaches the database. The rules are
+// compiled once and cached; Reload must be called whenever the settings change.
+
+// maxTitleLength caps the length of a stored title, in characters.
+const maxTitleLength = 256
+
+// Normalizer applies the title settings to window titles.
+type Normalizer struct {
+	enabled bool
+	rules   []rule
+}
+
+type rule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+var (
+	mu      sync.Mutex
+	current *Normalizer
+)
+
+// Current returns the normalizer of the configured settings.
+// If the settings can't be read or are invalid, title capture is disabled.
+func Current() *Normalizer {
+	mu.Lock()
+	defer mu.Unlock()
+	if current == nil {
+		n := &Normalizer{}
+		if cfg, err := config.LoadConfig(); err == nil {
+			if configured, err := New(cfg.Titles); err == nil {
+				n = configured
+			}
+		}
+		current = n
+	}
+	return current
+}
+
+// Reload makes the next Current read the settings again.
+func Reload() {
+	mu.Lock()
+	defer mu.Unlock()
+	current = nil
+}
+
+// New returns the normalizer of the given settings.
+func New(settings config.TitleSettings) (*Normalizer, error) {
+	n := &Normalizer{enabled: settings.Enabled}
+	for i, r := range settings.Rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in title rule %d: %w", i+1, err)
+		}
+		n.rules = append(n.rules, rule{re: re, replacement: r.Replacement})
+	}
+	return n, nil
+}
+
+// Validate checks title settings.
+func Validate(settings config.TitleSettings) error {
+	_, err := New(settings)
+	return err
+}
+
+// Enabled reports whether window titles are captured.
+func (n *Normalizer) Enabled() bool {
+	return n.enabled
+}
+
+// Normalize returns the title to store for a window title, or "" if none should be stored.
+func (n *Normalizer) Normalize(title string) string {
+	if !n.enabled {
+		return ""
+	}
+	for _, r := range n.rules {
+		title = r.re.ReplaceAllString(title, r.replacement)
+	}
+	title = strings.Join(strings.Fields(title), " ")
+	if utf8.RuneCountInString(title) > maxTitleLength {
+		title = string([]rune(title)[:maxTitleLength])
+	}
+	return title
+}
@@ -4,13 +4,19 @@ package main
 
 import (
 	"log"
+	"os"
 	"veda-anchor-engine/src/internal/config"
 
 	"golang.org/x/sys/windows/svc"
 )
 
 func main() {
-	// veda-anchor-engine runs exclusively as a Windows Service.
+	// Maintenance commands (backup, restore) run in the foreground and exit.
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+	}
+
+	// Otherwise veda-anchor-engine runs as a Windows Service.
 	// It is registered and started by the veda-anchor launcher (veda-anchor.exe).
 	err := svc.Run(config.ServiceName, &vedaAnchorService{})
 	if err != nil {
@@ -10,10 +10,15 @@ import (
 	"veda-anchor-engine/src/internal/agent"
 	"veda-anchor-engine/src/internal/config"
 	"veda-anchor-engine/src/internal/data"
+	"veda-anchor-engine/src/internal/data/backup"
 	"veda-anchor-engine/src/internal/data/logger"
+	"veda-anchor-engine/src/internal/data/retention"
+	"veda-anchor-engine/src/internal/data/spool"
 	"veda-anchor-engine/src/internal/ipc"
 	"veda-anchor-engine/src/internal/monitoring"
 	"veda-anchor-engine/src/internal/platform/nativehost"
+	"veda-anchor-engine/src/internal/service/appname"
+	"veda-anchor-engine/src/internal/service/catalog"
 
 	"golang.org/x/sys/windows/svc"
 )
@@ -53,8 +58,29 @@ func (s *vedaAnchorService) Execute(args []string, r <-chan svc.ChangeRequest, c
 	l := logger.GetLogger()
 	server := api.NewServer(db)
 
+	// Journal writes that fail while the database is locked, and replay journals from all processes
+	if err := spool.Install("engine"); err != nil {
+		log.Printf("Failed to set up write spool: %v", err)
+	}
+	spool.StartReplayer(db, l)
+
+	// Take automatic backups according to the backup settings
+	backup.StartScheduler(db, l)
+
+	// Prune raw history according to the retention settings
+	retention.StartPruner(db, l)
+
+	// Catalog the executables seen running, and fill in the commercial names of logged runs
+	catalog.Start(server.Apps, l)
+	appname.Start(server.Apps)
+
+	// Close the foreground interval left open by the previous run, unless the agent is still confirming it
+	if err := server.Apps.CloseStaleForeground(); err != nil {
+		log.Printf("Failed to close stale foreground intervals: %v", err)
+	}
+
 	// Start monitoring (screentime now handled by Agent)
-	monitoring.StartDefault(l, server.Apps, nil)
+	monitoring.StartDefault(l, server.Apps, server.Access, nil)
 
 	// Register Chrome extensions
 	if err := nativehost.RegisterExtension("hkanepohpflociaodcicmmfbdaohpceo"); err != nil {
@@ -87,7 +113,10 @@ func (s *vedaAnchorService) Execute(args []string, r <-chan svc.ChangeRequest, c
 		case svc.Stop, svc.Shutdown:
 			log.Printf("=== %s ENGINE SERVICE STOPPING ===", config.AppName)
 			changes <- svc.Status{State: svc.StopPending}
-			// Cleanup
+			// Cleanup: commit queued writes (including log lines) before closing the database
+			if err := data.FlushWrites(); err != nil {
+				log.Printf("Failed to flush database writes: %v", err)
+			}
 			l.Close()
 			_ = db.Close()
 			return false, 0